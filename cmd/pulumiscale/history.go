@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rshade/pulumi-scale/internal/audit"
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+// historyScanDepth is how many of the stack's most recent updates a pool's history searches.
+const historyScanDepth = 100
+
+func newHistoryCmd(root *rootOptions) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "history [pool]",
+		Short: "Show the stack's recent updates, or a pool's scaling history, as JSON",
+		Long: "Without a pool, show the stack's recent updates.\n\n" +
+			"With a pool, show its scaling history: the decisions recorded in --audit-log merged with " +
+			"the updates the scaler ran on the stack, newest first.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, state := root.open()
+			if len(args) == 0 {
+				updates, err := state.History(cmd.Context(), limit)
+				if err != nil {
+					return err
+				}
+				return printJSON(updates)
+			}
+
+			// Only reads the log; a missing file just means no decisions were recorded.
+			var auditLog *audit.Log
+			if root.auditLog != "" {
+				auditLog = audit.OpenReadOnly(root.auditLog)
+			}
+			entries, err := poolHistory(cmd.Context(), state, auditLog, root.stack().Name, args[0], limit)
+			if err != nil {
+				return err
+			}
+			return printJSON(entries)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of updates or history entries to show")
+	return cmd
+}
+
+// poolHistory merges the pool's audit records, if there is an audit log, with the scaler's
+// updates from the stack history.
+func poolHistory(ctx context.Context, state *autoscaler.StateManager, auditLog *audit.Log, stack, pool string, limit int) ([]audit.HistoryEntry, error) {
+	updates, err := state.ScalerUpdates(ctx, pool, historyScanDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stack history: %w", err)
+	}
+	var records []audit.Record
+	if auditLog != nil {
+		if records, err = auditLog.Query(audit.Query{Stack: stack, Pool: pool}); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+	entries := audit.History(stack, records, updates, limit)
+	if entries == nil {
+		entries = []audit.HistoryEntry{}
+	}
+	return entries, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/rshade/pulumi-scale/internal/audit"
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/notify"
+	"github.com/rshade/pulumi-scale/internal/tracing"
+)
+
+// envPrefix is prepended to a flag's upper-cased name to form its environment variable,
+// e.g. --rules-file is also read from PULUMISCALE_RULES_FILE.
+const envPrefix = "PULUMISCALE_"
+
+// rootOptions are the flags shared by every subcommand.
+type rootOptions struct {
+	stackName string
+	workDir   string
+	rulesFile string
+	escEnv    string
+	git       gitConfig
+	debug     bool
+	logFormat string
+
+	otlpEndpoint string
+	auditLog     string
+	notifyConfig string
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &rootOptions{}
+	cmd := &cobra.Command{
+		Use:           "pulumiscale",
+		Short:         "Autoscale Pulumi stacks from monitoring webhooks",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := bindEnv(cmd.Flags()); err != nil {
+				return err
+			}
+			if err := setupLogging(opts.debug, opts.logFormat); err != nil {
+				return err
+			}
+
+			shutdown, err := tracing.Setup(cmd.Context(), opts.otlpEndpoint)
+			if err != nil {
+				return err
+			}
+			// Finalizers also run when the command fails, so failed scales are exported too.
+			cobra.OnFinalize(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := shutdown(ctx); err != nil {
+					log.Warn().Err(err).Msg("Failed to flush traces")
+				}
+			})
+			return nil
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&opts.stackName, "stack", "dev", "The name of the Pulumi stack")
+	flags.StringVar(&opts.workDir, "workdir", ".", "The directory containing the Pulumi program")
+	flags.StringVar(&opts.rulesFile, "rules-file", "", "Optional YAML/JSON file with scaling rules (highest precedence)")
+	flags.StringVar(&opts.escEnv, "esc-env", "", "Optional Pulumi ESC environment with scaling rules under the 'pulumiscale' key")
+	flags.BoolVar(&opts.debug, "debug", false, "Enable debug logging")
+	flags.StringVar(&opts.logFormat, "log-format", "console", "Log format: console (human-readable) or json (one object per line)")
+	flags.StringVar(&opts.auditLog, "audit-log", "", "Append every scaling decision to this JSONL file")
+	flags.StringVar(&opts.notifyConfig, "notify-config", "", "YAML file with notification sinks for scaling events")
+	flags.StringVar(&opts.otlpEndpoint, "otlp-endpoint", "", "Export traces over OTLP/HTTP to this URL, e.g. http://localhost:4318")
+	flags.StringVar(&opts.git.URL, "git-url", "", "Clone the Pulumi program from this git repository instead of using --workdir")
+	flags.StringVar(&opts.git.Branch, "git-branch", "", "Branch or tag to follow (defaults to the remote's default branch)")
+	flags.StringVar(&opts.git.Commit, "git-commit", "", "Commit to pin; pinned checkouts are never pulled")
+	flags.StringVar(&opts.git.Path, "git-path", "", "Directory of the Pulumi program inside the repository")
+	flags.DurationVar(&opts.git.PullInterval, "git-pull-interval", defaultPullInterval, "How often to pull the branch for new commits (0 clones once)")
+	flags.StringVar(&opts.git.Token, "git-token", "", "Personal access token for private repositories")
+	flags.StringVar(&opts.git.SSHKeyPath, "git-ssh-key", "", "Path to an SSH private key for private repositories")
+
+	// Before subcommands existed the bare command ran the server. Keep `pulumiscale --port ...`
+	// working with a warning; serve's flags are accepted but hidden from the root's help.
+	serve := newServeCmd(opts)
+	serve.Flags().VisitAll(func(f *pflag.Flag) {
+		hidden := *f
+		hidden.Hidden = true
+		cmd.Flags().AddFlag(&hidden)
+	})
+	cmd.Args = cobra.NoArgs
+	cmd.RunE = func(c *cobra.Command, args []string) error {
+		log.Warn().Msg("Running pulumiscale without a subcommand is deprecated and will be removed; use `pulumiscale serve`")
+		return serve.RunE(c, args)
+	}
+
+	cmd.AddCommand(
+		serve,
+		newUpCmd(opts),
+		newScaleCmd(opts),
+		newStatusCmd(opts),
+		newValidateCmd(opts),
+		newHistoryCmd(opts),
+	)
+	return cmd
+}
+
+// bindEnv fills every flag that wasn't set on the command line from its environment variable.
+func bindEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if val, ok := os.LookupEnv(name); ok {
+			if setErr := flags.Set(f.Name, val); setErr != nil {
+				err = fmt.Errorf("invalid %s: %w", name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+func setupLogging(debug bool, format string) error {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	switch format {
+	case "console":
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	case "json":
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	default:
+		return fmt.Errorf("--log-format must be console or json, got %q", format)
+	}
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+	return nil
+}
+
+// stack returns the single stack selected by the shared flags.
+func (o *rootOptions) stack() stackConfig {
+	sc := stackConfig{
+		Name:      o.stackName,
+		Stack:     o.stackName,
+		WorkDir:   o.workDir,
+		RulesFile: o.rulesFile,
+		ESCEnv:    o.escEnv,
+	}
+	if o.git.URL != "" {
+		sc.Git = &o.git
+	}
+	return sc
+}
+
+// open returns the rule loader and state manager of the selected stack.
+func (o *rootOptions) open() (*autoscaler.ConfigLoader, *autoscaler.StateManager) {
+	return o.stack().open()
+}
+
+// openAudit opens the audit log, or returns nil when --audit-log isn't set.
+func (o *rootOptions) openAudit() (*audit.Log, error) {
+	if o.auditLog == "" {
+		return nil, nil
+	}
+	return audit.Open(o.auditLog)
+}
+
+// openNotifier loads the notification sinks, or returns nil when --notify-config isn't set.
+func (o *rootOptions) openNotifier() (*notify.Notifier, error) {
+	if o.notifyConfig == "" {
+		return nil, nil
+	}
+	return notify.Load(o.notifyConfig)
+}
+
+// exitCode makes main exit with a specific status without printing an error,
+// for commands whose output already explains the failure.
+type exitCode int
+
+func (c exitCode) Error() string { return fmt.Sprintf("exit status %d", int(c)) }
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
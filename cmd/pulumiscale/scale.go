@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func newScaleCmd(root *rootOptions) *cobra.Command {
+	var (
+		set    string
+		delta  float64
+		dryRun bool
+		reason string
+	)
+	cmd := &cobra.Command{
+		Use:   "scale <pool>",
+		Short: "Scale a pool once, applying the same guardrails as the webhooks",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			pool := args[0]
+			hasSet, hasDelta := cmd.Flags().Changed("set"), cmd.Flags().Changed("delta")
+			if hasSet == hasDelta {
+				return fmt.Errorf("exactly one of --set or --delta is required")
+			}
+
+			loader, state := root.open()
+			rules, err := loader.LoadRules(ctx)
+			if err != nil {
+				return err
+			}
+			rule, ok := rules[pool]
+			if !ok {
+				return fmt.Errorf("no rule found for pool %s", pool)
+			}
+
+			intent, err := scaleIntent(pool, rule, set, delta, hasSet)
+			if err != nil {
+				return err
+			}
+			intent.Reason = reason
+			intent.DryRun = dryRun
+
+			if u, err := user.Current(); err == nil {
+				intent.Requester = "cli:" + u.Username
+			}
+
+			engine := autoscaler.NewEngine(rules, state)
+			auditLog, err := root.openAudit()
+			if err != nil {
+				return err
+			}
+			if auditLog != nil {
+				defer auditLog.Close()
+				engine.Observe(auditLog.Observer(root.stack().Name))
+			}
+			notifier, err := root.openNotifier()
+			if err != nil {
+				return err
+			}
+			if notifier != nil {
+				defer notifier.Close()
+				engine.Observe(notifier.Observer(root.stack().Name))
+			}
+			result := engine.ProcessIntent(ctx, intent)
+			if err := printJSON(result); err != nil {
+				return err
+			}
+			if result.Status == autoscaler.JobFailed {
+				return exitCode(1)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&set, "set", "", "Set the pool to this value")
+	cmd.Flags().Float64Var(&delta, "delta", 0, "Change the pool by this amount")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview the change without applying it")
+	cmd.Flags().StringVar(&reason, "reason", "manual scale", "Reason recorded with the intent")
+	return cmd
+}
+
+// scaleIntent builds the intent for a --set or --delta run. Set values are sent as
+// text only to string pools; numeric pools need them parsed, like the webhooks do.
+func scaleIntent(pool string, rule autoscaler.ScalingRule, set string, delta float64, hasSet bool) (webhooks.ScalingIntent, error) {
+	intent := webhooks.ScalingIntent{
+		TargetPool: pool,
+		Action:     webhooks.ActionDelta,
+		Value:      delta,
+		Source:     "cli",
+	}
+	if !hasSet {
+		return intent, nil
+	}
+	intent = webhooks.ScalingIntent{TargetPool: pool, Action: webhooks.ActionSet, Source: "cli"}
+	if rule.Type == autoscaler.ValueString {
+		intent.Text = set
+		return intent, nil
+	}
+	v, err := strconv.ParseFloat(set, 64)
+	if err != nil {
+		return webhooks.ScalingIntent{}, fmt.Errorf("--set %q is not a number", set)
+	}
+	intent.Value = v
+	return intent, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestScaleIntent(t *testing.T) {
+	intRule := autoscaler.ScalingRule{PoolName: "workers", Type: autoscaler.ValueInt, Min: 1, Max: 10}
+	strRule := autoscaler.ScalingRule{PoolName: "size", Type: autoscaler.ValueString}
+
+	t.Run("set on an int pool", func(t *testing.T) {
+		intent, err := scaleIntent("workers", intRule, "5", 0, true)
+		if err != nil {
+			t.Fatalf("scaleIntent() error = %v", err)
+		}
+		if intent.Action != webhooks.ActionSet || intent.Value != 5 || intent.Text != "" {
+			t.Errorf("intent = %+v, want set 5 with no text", intent)
+		}
+	})
+
+	t.Run("set on a string pool", func(t *testing.T) {
+		intent, err := scaleIntent("size", strRule, "m5.large", 0, true)
+		if err != nil {
+			t.Fatalf("scaleIntent() error = %v", err)
+		}
+		if intent.Text != "m5.large" {
+			t.Errorf("Text = %q, want m5.large", intent.Text)
+		}
+	})
+
+	t.Run("set rejects text on an int pool", func(t *testing.T) {
+		if _, err := scaleIntent("workers", intRule, "five", 0, true); err == nil {
+			t.Error("scaleIntent() error = nil, want a number error")
+		}
+	})
+
+	t.Run("delta", func(t *testing.T) {
+		intent, err := scaleIntent("workers", intRule, "", -2, false)
+		if err != nil {
+			t.Fatalf("scaleIntent() error = %v", err)
+		}
+		if intent.Action != webhooks.ActionDelta || intent.Value != -2 || intent.TargetPool != "workers" {
+			t.Errorf("intent = %+v, want delta -2 on workers", intent)
+		}
+	})
+}
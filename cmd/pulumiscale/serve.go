@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+type serveOptions struct {
+	port           int
+	failFast       bool
+	maintenanceUp  bool
+	reloadInterval time.Duration
+	driftInterval  time.Duration
+	pinnedAfter    time.Duration
+	stacksFile     string
+	authToken      string
+}
+
+func newServeCmd(root *rootOptions) *cobra.Command {
+	opts := &serveOptions{}
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the webhook server and scale pools as events arrive",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runServe(cmd, root, opts)
+		},
+	}
+	cmd.Flags().IntVar(&opts.port, "port", 8080, "The port to listen on")
+	cmd.Flags().BoolVar(&opts.failFast, "fail-fast", false, "Exit at startup if scaling rules can't be loaded instead of waiting for a reload")
+	cmd.Flags().BoolVar(&opts.maintenanceUp, "maintenance-up", false, "Clamp every pool into its limits and run refresh+up before accepting events")
+	cmd.Flags().DurationVar(&opts.reloadInterval, "reload-interval", time.Minute, "How often to re-read scaling rules from the stack (0 disables polling)")
+	cmd.Flags().DurationVar(&opts.driftInterval, "drift-interval", 5*time.Minute, "How often to compare pools that have a drift config with live resource state (0 disables)")
+	cmd.Flags().DurationVar(&opts.pinnedAfter, "pinned-after", 15*time.Minute, "Alert when a pool stays clamped at its min or max this long while intents ask for more (0 disables; rules can override with pinnedAlert)")
+	cmd.Flags().StringVar(&opts.stacksFile, "stacks-file", "", "YAML file listing several stacks to serve from one process (overrides --stack)")
+	cmd.Flags().StringVar(&opts.authToken, "auth-token", "", "Bearer token required by the webhook, admin, pause/resume, drift check, audit and history endpoints (required)")
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, root *rootOptions, opts *serveOptions) error {
+	ctx := cmd.Context()
+	if opts.authToken == "" {
+		return fmt.Errorf("--auth-token (or %sAUTH_TOKEN) is required", envPrefix)
+	}
+
+	stacks := []stackConfig{root.stack()}
+	if opts.stacksFile != "" {
+		var err error
+		if stacks, err = loadStacksFile(opts.stacksFile); err != nil {
+			return err
+		}
+	}
+
+	server := NewServer(opts.port, opts.authToken)
+	auditLog, err := root.openAudit()
+	if err != nil {
+		return err
+	}
+	if auditLog != nil {
+		defer auditLog.Close()
+		server.RegisterAudit(auditLog)
+	}
+	if server.Notifier, err = root.openNotifier(); err != nil {
+		return err
+	}
+	if server.Notifier != nil {
+		// Deliver pending notifications once the server has stopped.
+		defer server.Notifier.Close()
+	}
+	runtimes := make([]*stackRuntime, 0, len(stacks))
+	for _, sc := range stacks {
+		rt, err := startStack(ctx, server, sc, opts)
+		if err != nil {
+			return err
+		}
+		runtimes = append(runtimes, rt)
+	}
+	server.RegisterAdmin(runtimes)
+	server.RegisterDrift(runtimes)
+	server.RegisterPools(runtimes)
+	server.RegisterHistory(runtimes)
+
+	// SIGHUP reloads rules; SIGINT/SIGTERM cancel ctx (see main) and shut the server down gracefully.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+				log.Info().Msg("Received SIGHUP, reloading scaling rules")
+				for _, rt := range runtimes {
+					if rt.reloader != nil {
+						rt.reloader.Trigger()
+					}
+				}
+			}
+		}
+	}()
+
+	log.Info().Int("port", opts.port).Int("stacks", len(runtimes)).Msg("Starting PulumiScale server...")
+	if err := server.Start(ctx); err != nil {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	return nil
+}
+
+// poolNames returns the sorted pool names of a rule set.
+func poolNames(rules map[string]autoscaler.ScalingRule) []string {
+	return slices.Sorted(maps.Keys(rules))
+}
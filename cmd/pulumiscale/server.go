@@ -2,42 +2,406 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog/log"
+
+	"github.com/rshade/pulumi-scale/internal/api"
+	"github.com/rshade/pulumi-scale/internal/audit"
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/metrics"
+	"github.com/rshade/pulumi-scale/internal/notify"
+	"github.com/rshade/pulumi-scale/internal/tracing"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+	"github.com/rshade/pulumi-scale/internal/webhooks/routers"
 )
 
 type Server struct {
-	Router *chi.Mux
-	Port   int
+	Router    *chi.Mux
+	Port      int
+	Readiness *api.Readiness
+	Metrics   *metrics.Metrics
+	Audit     *audit.Log       // nil unless enabled with RegisterAudit
+	Notifier  *notify.Notifier // nil unless --notify-config is set
+
+	// protected is Router behind the bearer token check, for routes that change or reveal scaling state.
+	protected chi.Router
+
+	// webhooks holds each stack's per-pool webhook router, rebuilt whenever its rules change.
+	webhooksMu sync.RWMutex
+	webhooks   map[string]*atomic.Pointer[chi.Mux]
+	stacks     []string
 }
 
-func NewServer(port int) *Server {
+// NewServer creates the server. Webhooks, the admin endpoints, pause/resume, drift checks
+// and the audit and history endpoints require "Authorization: Bearer <authToken>".
+func NewServer(port int, authToken string) *Server {
 	r := chi.NewRouter()
 
 	// Base middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	// r.Use(middleware.Logger) // Chi's default logger is not zerolog.
-	// We could use a custom zerolog middleware here, but for now we'll skip
-	// or rely on a simple request logger if needed.
-	// For this task, sticking to zerolog for app logs.
+	r.Use(api.RequesterMiddleware)
+	r.Use(api.RequestLogger(log.Logger))
+	r.Use(tracing.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	// Liveness: the process is up and serving HTTP.
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
-	return &Server{
-		Router: r,
-		Port:   port,
+	// Readiness: rules are loaded and the stack and engine are usable.
+	readiness := api.NewReadiness()
+	r.Get("/ready", readiness.Handler())
+
+	// The rules contract, for editors and CI validation.
+	r.Get("/schema/pulumiscale.v1.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(autoscaler.ContractSchema)
+	})
+
+	// Metrics about the autoscaler itself; stacks register their engines as they start.
+	m := metrics.New()
+	r.Get("/metrics", m.Handler().ServeHTTP)
+
+	s := &Server{
+		Router:    r,
+		Port:      port,
+		Readiness: readiness,
+		Metrics:   m,
+		protected: r.With(api.AuthMiddleware(authToken)),
+		webhooks:  make(map[string]*atomic.Pointer[chi.Mux]),
+	}
+	s.protected.Mount("/webhook", http.HandlerFunc(s.serveWebhook))
+	return s
+}
+
+// serveWebhook routes /webhook/{stack}/{pool}/... to the stack's router. When the server
+// manages a single stack, /webhook/{pool}/... is accepted as well.
+func (s *Server) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	rctx := chi.RouteContext(r.Context())
+	stack, rest, _ := strings.Cut(strings.TrimPrefix(rctx.RoutePath, "/"), "/")
+
+	s.webhooksMu.RLock()
+	ptr, ok := s.webhooks[stack]
+	if ok {
+		rctx.RoutePath = "/" + rest
+	} else if len(s.stacks) == 1 {
+		ptr, ok = s.webhooks[s.stacks[0]]
+	}
+	s.webhooksMu.RUnlock()
+	if !ok {
+		http.Error(w, "Unknown stack", http.StatusNotFound)
+		return
+	}
+
+	wr := ptr.Load()
+	if wr == nil {
+		http.Error(w, "No scaling rules loaded", http.StatusServiceUnavailable)
+		return
+	}
+	wr.ServeHTTP(w, r)
+}
+
+// AddStack declares a stack so its webhook routes answer 503 until RegisterWebhooks is called for it.
+func (s *Server) AddStack(stack string) {
+	s.webhooksMu.Lock()
+	defer s.webhooksMu.Unlock()
+	if _, ok := s.webhooks[stack]; !ok {
+		s.webhooks[stack] = &atomic.Pointer[chi.Mux]{}
+		s.stacks = append(s.stacks, stack)
+	}
+}
+
+// RegisterWebhooks (re-)registers the webhook routes for a stack's pools.
+// Requests for unknown pools get a 404. Safe to call while serving.
+func (s *Server) RegisterWebhooks(stack string, intentChan chan<- webhooks.ScalingIntent, pools []string) {
+	wr := routers.NewPoolRouter(intentChan, pools)
+	s.AddStack(stack)
+	s.webhooksMu.RLock()
+	s.webhooks[stack].Store(wr)
+	s.webhooksMu.RUnlock()
+	log.Info().Str("stack", stack).Strs("pools", pools).Msg("Registered webhook routes")
+}
+
+// RegisterReadinessChecks wires a stack's rule, stack and engine checks into /ready,
+// named "<stack>/rules", "<stack>/stack" and "<stack>/engine".
+func (s *Server) RegisterReadinessChecks(stack string, engine *autoscaler.Engine, state *autoscaler.StateManager, stackTTL time.Duration) {
+	s.AddStack(stack)
+	s.Readiness.Add(stack+"/rules", func(context.Context) error {
+		if n := len(engine.RulesSnapshot()); n == 0 {
+			return fmt.Errorf("no scaling rules loaded")
+		}
+		return nil
+	})
+	s.Readiness.Add(stack+"/stack", api.CachedCheck(func(ctx context.Context) error {
+		if err := state.Ping(ctx); err != nil {
+			return fmt.Errorf("stack unreachable: %w", err)
+		}
+		return nil
+	}, stackTTL))
+	s.Readiness.Add(stack+"/engine", func(context.Context) error {
+		if !engine.Running() {
+			return fmt.Errorf("engine loop is not running")
+		}
+		return nil
+	})
+}
+
+// RegisterAdmin adds the administrative endpoints. POST /admin/reload reloads every stack and
+// returns each stack's diff or error; POST /admin/reload/{stack} reloads one stack.
+func (s *Server) RegisterAdmin(runtimes []*stackRuntime) {
+	reload := func(ctx context.Context, rt *stackRuntime) (autoscaler.RuleDiff, error) {
+		if rt.reloader == nil {
+			return autoscaler.RuleDiff{}, fmt.Errorf("stack %s is not running", rt.config.Name)
+		}
+		return rt.reloader.Reload(ctx)
+	}
+
+	s.protected.Post("/admin/reload", func(w http.ResponseWriter, r *http.Request) {
+		type result struct {
+			autoscaler.RuleDiff
+			Error string `json:"error,omitempty"`
+		}
+		results := make(map[string]result, len(runtimes))
+		status := http.StatusOK
+		for _, rt := range runtimes {
+			diff, err := reload(r.Context(), rt)
+			res := result{RuleDiff: diff}
+			if err != nil {
+				res.Error = err.Error()
+				status = http.StatusInternalServerError
+			}
+			results[rt.config.Name] = res
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(results)
+	})
+	s.protected.Post("/admin/reload/{stack}", func(w http.ResponseWriter, r *http.Request) {
+		rt := findRuntime(runtimes, chi.URLParam(r, "stack"))
+		if rt == nil {
+			http.Error(w, "Unknown stack", http.StatusNotFound)
+			return
+		}
+		diff, err := reload(r.Context(), rt)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Reload failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(diff)
+	})
+}
+
+// RegisterDrift exposes the drift checkers: GET /drift returns the latest status of every pool
+// with a drift config, keyed by stack, and POST /drift/check runs a check now.
+func (s *Server) RegisterDrift(runtimes []*stackRuntime) {
+	collect := func(check func(*autoscaler.DriftChecker) []autoscaler.DriftStatus) map[string][]autoscaler.DriftStatus {
+		out := make(map[string][]autoscaler.DriftStatus, len(runtimes))
+		for _, rt := range runtimes {
+			if rt.drift != nil {
+				out[rt.config.Name] = check(rt.drift)
+			}
+		}
+		return out
+	}
+	s.Router.Get("/drift", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(collect((*autoscaler.DriftChecker).Statuses))
+	})
+	s.protected.Post("/drift/check", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(collect(func(d *autoscaler.DriftChecker) []autoscaler.DriftStatus {
+			return d.Check(r.Context())
+		}))
+	})
+}
+
+// RegisterAudit records the decisions of stacks started afterwards in l and serves them:
+// GET /audit returns the records matching the optional stack, pool, since and limit parameters.
+// since is an RFC 3339 time or a duration before now, e.g. "24h".
+func (s *Server) RegisterAudit(l *audit.Log) {
+	s.Audit = l
+	s.protected.Get("/audit", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := audit.Query{Stack: params.Get("stack"), Pool: params.Get("pool")}
+		if v := params.Get("since"); v != "" {
+			since, err := parseSince(v, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			q.Since = since
+		}
+		if v := params.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 0 {
+				http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			q.Limit = limit
+		}
+		records, err := l.Query(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []audit.Record{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+	})
+}
+
+// parseSince reads an RFC 3339 time or a duration before now.
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("since must be an RFC 3339 time or a positive duration, got %q", v)
 	}
+	return now.Add(-d), nil
+}
+
+// RegisterHistory serves GET /pools/{pool}/history, the pool's scaling history from the
+// audit log, if enabled, and the stack's update history. With several stacks, pass ?stack=
+// unless only one stack has the pool.
+func (s *Server) RegisterHistory(runtimes []*stackRuntime) {
+	s.protected.Get("/pools/{pool}/history", func(w http.ResponseWriter, r *http.Request) {
+		pool := chi.URLParam(r, "pool")
+		rt, status, err := poolRuntime(runtimes, r.URL.Query().Get("stack"), pool)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+				http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+		}
+		entries, err := poolHistory(r.Context(), rt.state, s.Audit, rt.config.Name, pool, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	})
+}
+
+// stackPoolStatus is a pool's status tagged with its stack.
+type stackPoolStatus struct {
+	Stack string `json:"stack"`
+	autoscaler.PoolStatus
+}
+
+// RegisterPools serves the pools' runtime state: GET /pools lists every pool of every stack,
+// GET /pools/{pool} returns one, and POST /pools/{pool}/pause and /resume stop and restart
+// scaling it. As with the history, pass ?stack= when several stacks have the pool.
+func (s *Server) RegisterPools(runtimes []*stackRuntime) {
+	s.Router.Get("/pools", func(w http.ResponseWriter, r *http.Request) {
+		out := []stackPoolStatus{}
+		for _, rt := range runtimes {
+			for _, st := range rt.engine.Status(r.Context()) {
+				out = append(out, stackPoolStatus{Stack: rt.config.Name, PoolStatus: st})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+
+	// withPool resolves the pool's runtime and 404s pools without a rule.
+	withPool := func(fn func(w http.ResponseWriter, r *http.Request, rt *stackRuntime, pool string)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			pool := chi.URLParam(r, "pool")
+			rt, status, err := poolRuntime(runtimes, r.URL.Query().Get("stack"), pool)
+			if err != nil {
+				http.Error(w, err.Error(), status)
+				return
+			}
+			if _, ok := rt.engine.RulesSnapshot()[pool]; !ok {
+				http.Error(w, fmt.Sprintf("no rule found for pool %s", pool), http.StatusNotFound)
+				return
+			}
+			fn(w, r, rt, pool)
+		}
+	}
+	writeStatus := func(w http.ResponseWriter, r *http.Request, rt *stackRuntime, pool string) {
+		st, ok := rt.engine.PoolStatus(r.Context(), pool)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no rule found for pool %s", pool), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stackPoolStatus{Stack: rt.config.Name, PoolStatus: st})
+	}
+	s.Router.Get("/pools/{pool}", withPool(writeStatus))
+	s.protected.Post("/pools/{pool}/pause", withPool(func(w http.ResponseWriter, r *http.Request, rt *stackRuntime, pool string) {
+		rt.engine.Pause(pool)
+		log.Info().Str("stack", rt.config.Name).Str("pool", pool).Msg("Pool paused")
+		writeStatus(w, r, rt, pool)
+	}))
+	s.protected.Post("/pools/{pool}/resume", withPool(func(w http.ResponseWriter, r *http.Request, rt *stackRuntime, pool string) {
+		rt.engine.Resume(pool)
+		log.Info().Str("stack", rt.config.Name).Str("pool", pool).Msg("Pool resumed")
+		writeStatus(w, r, rt, pool)
+	}))
+}
+
+// poolRuntime returns the runtime of the named stack or, when stack is empty, of the only
+// stack that has the pool, with the HTTP status to report if there is none.
+func poolRuntime(runtimes []*stackRuntime, stack, pool string) (*stackRuntime, int, error) {
+	if stack != "" {
+		if rt := findRuntime(runtimes, stack); rt != nil {
+			return rt, http.StatusOK, nil
+		}
+		return nil, http.StatusNotFound, fmt.Errorf("unknown stack %s", stack)
+	}
+	if len(runtimes) == 1 {
+		return runtimes[0], http.StatusOK, nil
+	}
+	var found []*stackRuntime
+	for _, rt := range runtimes {
+		if _, ok := rt.engine.RulesSnapshot()[pool]; ok {
+			found = append(found, rt)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, http.StatusNotFound, fmt.Errorf("no stack has pool %s", pool)
+	case 1:
+		return found[0], http.StatusOK, nil
+	default:
+		return nil, http.StatusBadRequest, fmt.Errorf("pool %s exists in several stacks; pass ?stack=", pool)
+	}
+}
+
+// findRuntime returns the runtime of the named stack, or nil.
+func findRuntime(runtimes []*stackRuntime, name string) *stackRuntime {
+	for _, rt := range runtimes {
+		if rt.config.Name == name {
+			return rt
+		}
+	}
+	return nil
 }
 
 func (s *Server) Start(ctx context.Context) error {
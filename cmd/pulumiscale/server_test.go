@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rshade/pulumi-scale/internal/audit"
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+const testToken = "s3cret"
+
+// authed returns a request carrying the test token.
+func authed(method, path string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	return req
+}
+
+// post sends a delta webhook to s and returns the response status.
+func post(s *Server, path string) int {
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, authed("POST", path, strings.NewReader(`{"delta": 1}`)))
+	return w.Code
+}
+
+func TestServeWebhook(t *testing.T) {
+	t.Run("routes by stack", func(t *testing.T) {
+		s := NewServer(0, testToken)
+		prod, staging := make(chan webhooks.ScalingIntent, 1), make(chan webhooks.ScalingIntent, 1)
+		s.RegisterWebhooks("prod", prod, []string{"workers"})
+		s.RegisterWebhooks("staging", staging, []string{"workers"})
+
+		if code := post(s, "/webhook/staging/workers/delta"); code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", code)
+		}
+		select {
+		case intent := <-staging:
+			if intent.TargetPool != "workers" {
+				t.Errorf("pool = %q, want workers", intent.TargetPool)
+			}
+		default:
+			t.Fatal("intent not sent to the staging engine")
+		}
+		if len(prod) != 0 {
+			t.Error("intent sent to the prod engine")
+		}
+
+		// With several stacks the stack segment is required.
+		if code := post(s, "/webhook/workers/delta"); code != http.StatusNotFound {
+			t.Errorf("unqualified path status = %d, want 404", code)
+		}
+		if code := post(s, "/webhook/prod/api/delta"); code != http.StatusNotFound {
+			t.Errorf("unknown pool status = %d, want 404", code)
+		}
+	})
+
+	t.Run("single stack accepts unqualified paths", func(t *testing.T) {
+		s := NewServer(0, testToken)
+		ch := make(chan webhooks.ScalingIntent, 2)
+		s.RegisterWebhooks("dev", ch, []string{"workers"})
+
+		for _, path := range []string{"/webhook/workers/delta", "/webhook/dev/workers/delta"} {
+			if code := post(s, path); code != http.StatusOK {
+				t.Errorf("%s status = %d, want 200", path, code)
+			}
+		}
+		if len(ch) != 2 {
+			t.Errorf("queued %d intents, want 2", len(ch))
+		}
+	})
+
+	t.Run("failed maintenance only stops its stack", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := NewServer(0, testToken)
+		healthy := make(chan webhooks.ScalingIntent, 1)
+		s.RegisterWebhooks("healthy", healthy, []string{"workers"})
+
+		// An empty work directory has no Pulumi project, so loading and reconciling fail.
+		broken := stackConfig{Name: "broken", Stack: "broken", WorkDir: t.TempDir()}
+		rt, err := startStack(ctx, s, broken, &serveOptions{maintenanceUp: true})
+		if err != nil {
+			t.Fatalf("startStack() error = %v, want the stack left stopped", err)
+		}
+		if rt.reloader != nil || rt.engine.Running() {
+			t.Error("broken stack was started")
+		}
+
+		if code := post(s, "/webhook/broken/workers/delta"); code != http.StatusServiceUnavailable {
+			t.Errorf("broken stack status = %d, want 503", code)
+		}
+		if code := post(s, "/webhook/healthy/workers/delta"); code != http.StatusOK {
+			t.Errorf("healthy stack status = %d, want 200", code)
+		}
+		if len(healthy) != 1 {
+			t.Error("healthy stack didn't receive its intent")
+		}
+	})
+}
+
+func TestPoolsPauseResume(t *testing.T) {
+	rules := map[string]autoscaler.ScalingRule{"workers": {PoolName: "workers", ConfigKey: "workerCount", Max: 10}}
+	engines := map[string]*autoscaler.Engine{
+		"prod":    autoscaler.NewEngine(rules, nil),
+		"staging": autoscaler.NewEngine(rules, nil),
+	}
+	s := NewServer(0, testToken)
+	s.RegisterPools([]*stackRuntime{
+		{config: stackConfig{Name: "prod"}, engine: engines["prod"]},
+		{config: stackConfig{Name: "staging"}, engine: engines["staging"]},
+	})
+	do := func(path string) (int, stackPoolStatus) {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, authed("POST", path, nil))
+		var st stackPoolStatus
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+				t.Fatalf("%s: invalid JSON: %v", path, err)
+			}
+		}
+		return w.Code, st
+	}
+
+	code, st := do("/pools/workers/pause?stack=staging")
+	if code != http.StatusOK || !st.Paused || st.Stack != "staging" {
+		t.Fatalf("pause = %d, %+v; want staging paused", code, st)
+	}
+	if !engines["staging"].Paused("workers") || engines["prod"].Paused("workers") {
+		t.Error("pause didn't apply to the staging pool only")
+	}
+
+	code, st = do("/pools/workers/resume?stack=staging")
+	if code != http.StatusOK || st.Paused || engines["staging"].Paused("workers") {
+		t.Errorf("resume = %d, %+v; want the pool resumed", code, st)
+	}
+
+	// The pool is in both stacks, so the stack must be named; unknown pools and stacks 404.
+	if code, _ := do("/pools/workers/pause"); code != http.StatusBadRequest {
+		t.Errorf("ambiguous pause = %d, want 400", code)
+	}
+	if code, _ := do("/pools/api/pause?stack=prod"); code != http.StatusNotFound {
+		t.Errorf("unknown pool pause = %d, want 404", code)
+	}
+	if code, _ := do("/pools/workers/pause?stack=dev"); code != http.StatusNotFound {
+		t.Errorf("unknown stack pause = %d, want 404", code)
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	rules := map[string]autoscaler.ScalingRule{"workers": {PoolName: "workers", ConfigKey: "workerCount", Max: 10}}
+	engine := autoscaler.NewEngine(rules, nil)
+	runtimes := []*stackRuntime{{config: stackConfig{Name: "dev"}, engine: engine}}
+	s := NewServer(0, testToken)
+	s.RegisterWebhooks("dev", make(chan webhooks.ScalingIntent, 1), []string{"workers"})
+	l, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s.RegisterAudit(l)
+	s.RegisterAdmin(runtimes)
+	s.RegisterDrift(runtimes)
+	s.RegisterPools(runtimes)
+	s.RegisterHistory(runtimes)
+
+	protected := []struct{ method, path string }{
+		{"POST", "/webhook/workers/delta"},
+		{"POST", "/admin/reload"},
+		{"POST", "/admin/reload/dev"},
+		{"POST", "/pools/workers/pause"},
+		{"POST", "/pools/workers/resume"},
+		{"POST", "/drift/check"},
+		{"GET", "/audit"},
+		{"GET", "/pools/workers/history"},
+	}
+	for _, tt := range protected {
+		for name, header := range map[string]string{"no token": "", "wrong token": "Bearer nope", "bare token": testToken} {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			s.Router.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s with %s = %d, want 401", tt.method, tt.path, name, w.Code)
+			}
+		}
+	}
+	if engine.Paused("workers") {
+		t.Error("unauthenticated pause paused the pool")
+	}
+
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, authed("GET", "/audit", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /audit with the token = %d, want 200", w.Code)
+	}
+	for _, path := range []string{"/health", "/pools", "/pools/workers", "/drift"} {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s without a token = %d, want 200", path, w.Code)
+		}
+	}
+}
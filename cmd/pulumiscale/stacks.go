@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+// stackConfig is one stack managed by the server.
+type stackConfig struct {
+	// Name namespaces the stack's routes (/webhook/{name}/{pool}/...). Defaults to Stack.
+	Name  string `yaml:"name"`
+	Stack string `yaml:"stack"`
+
+	// Project is only used to check that rule URNs belong to the project. It doesn't select
+	// the program (WorkDir or Git does) or qualify Stack; use org/project/stack for that.
+	Project   string `yaml:"project"`
+	WorkDir   string `yaml:"workdir"`
+	RulesFile string `yaml:"rulesFile"`
+	ESCEnv    string `yaml:"escEnv"`
+
+	// (Optional) Clone the program from git; WorkDir is then ignored.
+	Git *gitConfig `yaml:"git"`
+}
+
+// defaultPullInterval is how often a git-sourced program is pulled unless configured,
+// both with --git-pull-interval and in a stacks file.
+const defaultPullInterval = 5 * time.Minute
+
+// gitConfig clones the stack's program from a git repository instead of using WorkDir.
+type gitConfig struct {
+	URL          string        `yaml:"url"`
+	Branch       string        `yaml:"branch"`
+	Commit       string        `yaml:"commit"`
+	Path         string        `yaml:"path"`
+	PullInterval time.Duration `yaml:"pullInterval"`
+	Token        string        `yaml:"token"`
+	SSHKeyPath   string        `yaml:"sshKey"`
+}
+
+// UnmarshalYAML defaults pullInterval like the flag does; an explicit 0 still clones once.
+func (g *gitConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain gitConfig
+	p := plain{PullInterval: defaultPullInterval}
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	*g = gitConfig(p)
+	return nil
+}
+
+func (g *gitConfig) source() autoscaler.GitSource {
+	src := autoscaler.GitSource{
+		URL:          g.URL,
+		Branch:       g.Branch,
+		Commit:       g.Commit,
+		ProjectPath:  g.Path,
+		PullInterval: g.PullInterval,
+	}
+	if g.Token != "" || g.SSHKeyPath != "" {
+		src.Auth = &auto.GitAuth{PersonalAccessToken: g.Token, SSHPrivateKeyPath: g.SSHKeyPath}
+	}
+	return src
+}
+
+// open builds the stack's state manager and rule loader. Both share one stack handle,
+// so a git-sourced stack is cloned once.
+// Rule precedence (highest first): rules file, ESC environment, pulumiscale:rules config, pulumiscale output.
+func (c stackConfig) open() (*autoscaler.ConfigLoader, *autoscaler.StateManager) {
+	var (
+		loader *autoscaler.ConfigLoader
+		state  *autoscaler.StateManager
+	)
+	if c.Git != nil {
+		state = autoscaler.NewGitStateManager(c.Stack, c.Git.source())
+		loader = autoscaler.NewConfigLoaderFor(state)
+		loader.AddSource(autoscaler.NewConfigSourceFor(state))
+	} else {
+		state = autoscaler.NewStateManager(c.Stack, c.WorkDir)
+		loader = autoscaler.NewConfigLoader(c.Stack, c.WorkDir)
+		loader.AddSource(autoscaler.NewConfigSource(c.Stack, c.WorkDir))
+	}
+	loader.Project = c.Project
+	if c.ESCEnv != "" {
+		loader.AddSource(autoscaler.NewESCSource(c.ESCEnv))
+	}
+	if c.RulesFile != "" {
+		loader.AddSource(&autoscaler.FileSource{Path: c.RulesFile})
+	}
+	return loader, state
+}
+
+var stackNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// loadStacksFile reads the stacks served by one process from a YAML file:
+//
+//	stacks:
+//	  - name: prod-us-east-1
+//	    stack: acme/infra/prod-us-east-1
+//	    workdir: ./infra
+func loadStacksFile(path string) ([]stackConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Stacks []stackConfig `yaml:"stacks"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(file.Stacks) == 0 {
+		return nil, fmt.Errorf("%s defines no stacks", path)
+	}
+
+	seen := make(map[string]bool, len(file.Stacks))
+	for i := range file.Stacks {
+		sc := &file.Stacks[i]
+		if sc.Stack == "" {
+			return nil, fmt.Errorf("%s: stacks[%d]: stack is required", path, i)
+		}
+		if sc.Name == "" {
+			sc.Name = sc.Stack
+		}
+		if !stackNameRe.MatchString(sc.Name) {
+			return nil, fmt.Errorf("%s: stacks[%d]: name %q must be a single path segment; set name for fully qualified stacks", path, i, sc.Name)
+		}
+		if seen[sc.Name] {
+			return nil, fmt.Errorf("%s: duplicate stack name %q", path, sc.Name)
+		}
+		seen[sc.Name] = true
+		if sc.WorkDir == "" {
+			sc.WorkDir = "."
+		}
+	}
+	return file.Stacks, nil
+}
+
+// stackRuntime is everything the server runs for one stack. Each stack has its own engine loop,
+// lock and reloader, so a slow or failing stack never blocks the others.
+type stackRuntime struct {
+	config   stackConfig
+	state    *autoscaler.StateManager
+	engine   *autoscaler.Engine
+	reloader *autoscaler.Reloader
+	drift    *autoscaler.DriftChecker
+}
+
+// startStack loads the stack's rules, optionally reconciles it, and starts its engine,
+// reloader and drift checker. An error is only returned when the stack must not start.
+func startStack(ctx context.Context, server *Server, sc stackConfig, opts *serveOptions) (*stackRuntime, error) {
+	logger := log.With().Str("stack", sc.Name).Logger()
+
+	// Load configuration
+	logger.Info().Str("workdir", sc.WorkDir).Msg("Loading scaling rules...")
+	loader, state := sc.open()
+	rules, err := loader.LoadRules(ctx)
+	if err != nil {
+		if opts.failFast {
+			return nil, fmt.Errorf("stack %s: failed to load rules (ensure the stack exists and has outputs): %w", sc.Name, err)
+		}
+		logger.Warn().Err(err).Msg("Failed to load rules. (Ensure stack exists and has outputs)")
+	} else {
+		logger.Info().Int("count", len(rules)).Msg("Loaded scaling rules")
+		for name, rule := range rules {
+			logger.Info().
+				Str("pool", name).
+				Str("targetUrn", rule.TargetURN).
+				Float64("min", rule.Min).
+				Float64("max", rule.Max).
+				Msg("Rule loaded")
+		}
+	}
+
+	rt := &stackRuntime{config: sc, state: state}
+	rt.engine = autoscaler.NewEngine(rules, rt.state)
+	rt.engine.PinnedAfter = opts.pinnedAfter
+	server.Metrics.AddEngine(sc.Name, rt.engine)
+	if server.Audit != nil {
+		rt.engine.Observe(server.Audit.Observer(sc.Name))
+	}
+	if server.Notifier != nil {
+		rt.engine.Observe(server.Notifier.Observer(sc.Name))
+	}
+	server.RegisterReadinessChecks(sc.Name, rt.engine, rt.state, 30*time.Second)
+
+	// Maintenance mode: the baseline must be correct before any scaling event is accepted.
+	if opts.maintenanceUp {
+		if err == nil {
+			_, err = reconcile(ctx, rt.state, rules)
+		}
+		if err != nil {
+			if opts.failFast {
+				return nil, fmt.Errorf("stack %s: %w", sc.Name, err)
+			}
+			// Leave the engine stopped: /ready reports the stack and its webhooks return 503.
+			logger.Error().Err(err).Msg("Maintenance up failed; not accepting events for this stack")
+			return rt, nil
+		}
+	}
+
+	go rt.engine.Start(ctx)
+	server.RegisterWebhooks(sc.Name, rt.engine.IntentChan, poolNames(rules))
+
+	// Keep rules in sync with the stack output. This also recovers from a failed initial load.
+	rt.reloader = autoscaler.NewReloader(loader, rt.engine, opts.reloadInterval)
+	rt.reloader.OnChange = func(rules map[string]autoscaler.ScalingRule) {
+		server.RegisterWebhooks(sc.Name, rt.engine.IntentChan, poolNames(rules))
+	}
+	go rt.reloader.Run(ctx)
+
+	rt.drift = autoscaler.NewDriftChecker(rt.engine, opts.driftInterval)
+	server.Metrics.AddDriftChecker(sc.Name, rt.drift)
+	go rt.drift.Run(ctx)
+	return rt, nil
+}
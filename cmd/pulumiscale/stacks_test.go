@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadStacksFile(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "stacks.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		stacks, err := loadStacksFile(write(t, `
+stacks:
+  - stack: dev
+  - name: prod-us-east-1
+    stack: acme/infra/prod-us-east-1
+    project: infra
+    workdir: ./infra
+`))
+		if err != nil {
+			t.Fatalf("loadStacksFile() error = %v", err)
+		}
+		if len(stacks) != 2 {
+			t.Fatalf("got %d stacks, want 2", len(stacks))
+		}
+		if dev := stacks[0]; dev.Name != "dev" || dev.WorkDir != "." {
+			t.Errorf("dev = %+v, want name dev in the current directory", dev)
+		}
+		if prod := stacks[1]; prod.Name != "prod-us-east-1" || prod.Project != "infra" || prod.WorkDir != "./infra" {
+			t.Errorf("prod = %+v", prod)
+		}
+	})
+
+	t.Run("git pull interval", func(t *testing.T) {
+		stacks, err := loadStacksFile(write(t, `
+stacks:
+  - stack: dev
+    git: {url: https://example.com/infra.git}
+  - stack: prod
+    git: {url: https://example.com/infra.git, pullInterval: 0s}
+  - stack: staging
+    git: {url: https://example.com/infra.git, pullInterval: 1m}
+`))
+		if err != nil {
+			t.Fatalf("loadStacksFile() error = %v", err)
+		}
+		want := []time.Duration{defaultPullInterval, 0, time.Minute}
+		for i, sc := range stacks {
+			if sc.Git.PullInterval != want[i] {
+				t.Errorf("%s pullInterval = %v, want %v", sc.Name, sc.Git.PullInterval, want[i])
+			}
+		}
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no stacks", content: "stacks: []", wantErr: "defines no stacks"},
+		{name: "missing stack", content: "stacks:\n  - name: prod", wantErr: "stack is required"},
+		{name: "qualified name", content: "stacks:\n  - stack: acme/infra/prod", wantErr: "single path segment"},
+		{name: "invalid name", content: "stacks:\n  - name: ../prod\n    stack: prod", wantErr: "single path segment"},
+		{name: "duplicate name", content: "stacks:\n  - stack: prod\n  - name: prod\n    stack: acme/infra/prod", wantErr: `duplicate stack name "prod"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadStacksFile(write(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadStacksFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
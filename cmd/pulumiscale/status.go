@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+func newStatusCmd(root *rootOptions) *cobra.Command {
+	var (
+		output string
+		server string
+	)
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show each pool's current value, limits and runtime state",
+		Long: "Show each pool's rule, current value, last scale and cooldown.\n\n" +
+			"With --server, the state is read from a running `pulumiscale serve` (GET /pools), which also " +
+			"knows in-flight jobs, paused pools and the last error. Otherwise the stack is read directly " +
+			"and the last scale comes from the stack's update history.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("--output must be table or json, got %q", output)
+			}
+			ctx := cmd.Context()
+
+			var statuses []stackPoolStatus
+			if server != "" {
+				var err error
+				if statuses, err = fetchStatus(cmd, server); err != nil {
+					return err
+				}
+			} else {
+				loader, state := root.open()
+				rules, err := loader.LoadRules(ctx)
+				if err != nil {
+					return err
+				}
+				engine := autoscaler.NewEngine(rules, state)
+				if err := engine.LoadLastScaled(ctx, historyScanDepth); err != nil {
+					log.Warn().Err(err).Msg("Could not read stack history; last scale times are unknown")
+				}
+				for _, st := range engine.Status(ctx) {
+					statuses = append(statuses, stackPoolStatus{Stack: root.stack().Name, PoolStatus: st})
+				}
+			}
+
+			if output == "json" {
+				if statuses == nil {
+					statuses = []stackPoolStatus{}
+				}
+				return printJSON(statuses)
+			}
+			return printStatusTable(os.Stdout, statuses)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&server, "server", "", "URL of a running server to read the state from, e.g. http://localhost:8080")
+	return cmd
+}
+
+// fetchStatus reads GET /pools from a running server.
+func fetchStatus(cmd *cobra.Command, server string) ([]stackPoolStatus, error) {
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, strings.TrimSuffix(server, "/")+"/pools", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GET /pools: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var statuses []stackPoolStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return nil, fmt.Errorf("GET /pools: %w", err)
+	}
+	return statuses, nil
+}
+
+// printStatusTable renders statuses as aligned columns. The stack column is only shown
+// when there is more than one stack.
+func printStatusTable(w io.Writer, statuses []stackPoolStatus) error {
+	multiStack := false
+	for _, st := range statuses {
+		if st.Stack != statuses[0].Stack {
+			multiStack = true
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := "POOL\tCURRENT\tMIN\tMAX\tLAST SCALED\tCOOLDOWN\tIN FLIGHT\tPAUSED\tPINNED\tLAST ERROR"
+	if multiStack {
+		header = "STACK\t" + header
+	}
+	fmt.Fprintln(tw, header)
+	for _, st := range statuses {
+		current := st.Current.String()
+		if st.CurrentError != "" {
+			current = "?"
+		}
+		limits := []string{"-", "-"}
+		if st.Rule.Type != autoscaler.ValueString {
+			limits = []string{formatNumber(st.Rule.Min), formatNumber(st.Rule.Max)}
+		}
+		lastScaled := "-"
+		if !st.LastScaled.IsZero() {
+			lastScaled = st.LastScaled.Local().Format(time.DateTime)
+		}
+		cooldown := "-"
+		if st.CooldownRemaining > 0 {
+			cooldown = (time.Duration(st.CooldownRemaining) * time.Second).String()
+		}
+		inFlight := "-"
+		if job := st.InFlight; job != nil {
+			inFlight = string(job.Action)
+			if job.Source != "" {
+				inFlight += " via " + job.Source
+			}
+		}
+		pinned := "-"
+		if p := st.Pinned; p != nil {
+			pinned = fmt.Sprintf("%s for %s", p.Bound, time.Since(p.Since).Round(time.Second))
+		}
+		lastError := st.LastError
+		if lastError == "" {
+			lastError = st.CurrentError
+		}
+		if lastError == "" {
+			lastError = "-"
+		}
+		row := []string{st.Pool, current, limits[0], limits[1], lastScaled, cooldown, inFlight, fmt.Sprint(st.Paused), pinned, lastError}
+		if multiStack {
+			row = append([]string{st.Stack}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatNumber(f float64) string {
+	return autoscaler.FloatValue(f).Raw()
+}
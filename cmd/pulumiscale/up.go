@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+func newUpCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "up",
+		Short: "Clamp every pool into its limits and run refresh+up to restore the baseline (maintenance mode)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			loader, state := root.open()
+			rules, err := loader.LoadRules(cmd.Context())
+			if err != nil {
+				return err
+			}
+			res, err := reconcile(cmd.Context(), state, rules)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+}
+
+// reconcile runs maintenance mode and logs the outcome.
+func reconcile(ctx context.Context, state *autoscaler.StateManager, rules map[string]autoscaler.ScalingRule) (autoscaler.ReconcileResult, error) {
+	log.Info().Str("stack", state.StackName).Int("pools", len(rules)).Msg("Reconciling stack baseline...")
+	res, err := state.Reconcile(ctx, rules)
+	if err != nil {
+		return res, fmt.Errorf("maintenance up failed: %w", err)
+	}
+	log.Info().Interface("changes", res.Changes).Msg("Stack baseline restored")
+	return res, nil
+}
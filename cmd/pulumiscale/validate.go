@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+// Exit codes of `pulumiscale validate`. Other failures exit with 1 as well.
+const (
+	exitInvalid exitCode = 1
+	exitError   exitCode = 2
+)
+
+func newValidateCmd(root *rootOptions) *cobra.Command {
+	var offline bool
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Lint scaling rules and print a JSON report",
+		Long: `Lint scaling rules and print a JSON report to stdout.
+
+By default the rules are loaded from the stack (plus any --rules-file/--esc-env) and checked
+against its state and config. With --offline only --rules-file/--esc-env are checked against
+the contract. Exits 1 when the rules are invalid and 2 when validation could not run.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// Keep stderr quiet so CI output is just the report and real errors.
+			zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+			linter := &autoscaler.Linter{}
+			if offline {
+				if root.rulesFile == "" && root.escEnv == "" {
+					return fmt.Errorf("--offline requires --rules-file or --esc-env")
+				}
+				linter.Loader = &autoscaler.ConfigLoader{}
+				if root.escEnv != "" {
+					linter.Loader.AddSource(autoscaler.NewESCSource(root.escEnv))
+				}
+				if root.rulesFile != "" {
+					linter.Loader.AddSource(&autoscaler.FileSource{Path: root.rulesFile})
+				}
+			} else {
+				linter.Loader, linter.State = root.open()
+			}
+
+			report, err := linter.Lint(cmd.Context())
+			if err != nil {
+				cmd.PrintErrln("Error:", err)
+				return exitError
+			}
+			if err := printJSON(report); err != nil {
+				return err
+			}
+			if !report.Valid {
+				return exitInvalid
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&offline, "offline", false, "Only check the rules file or ESC environment against the contract, without the stack")
+	return cmd
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check is a single readiness check. A nil error means the check passed.
+type Check func(ctx context.Context) error
+
+// CheckResult is the JSON outcome of one check.
+type CheckResult struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReadinessReport is the JSON body served by the readiness endpoint.
+type ReadinessReport struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Readiness aggregates named checks into a readiness endpoint.
+type Readiness struct {
+	mu     sync.Mutex
+	names  []string
+	checks map[string]Check
+
+	// Timeout bounds each evaluation of all checks.
+	Timeout time.Duration
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{
+		checks:  make(map[string]Check),
+		Timeout: 5 * time.Second,
+	}
+}
+
+// Add registers a check under name, replacing any existing check with that name.
+func (rd *Readiness) Add(name string, check Check) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if _, ok := rd.checks[name]; !ok {
+		rd.names = append(rd.names, name)
+	}
+	rd.checks[name] = check
+}
+
+// Evaluate runs every check and reports whether all passed.
+func (rd *Readiness) Evaluate(ctx context.Context) ReadinessReport {
+	rd.mu.Lock()
+	names := append([]string(nil), rd.names...)
+	checks := make(map[string]Check, len(rd.checks))
+	for k, v := range rd.checks {
+		checks[k] = v
+	}
+	rd.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, rd.Timeout)
+	defer cancel()
+
+	report := ReadinessReport{Ready: true, Checks: make(map[string]CheckResult, len(names))}
+	for _, name := range names {
+		if err := checks[name](ctx); err != nil {
+			report.Ready = false
+			report.Checks[name] = CheckResult{OK: false, Message: err.Error()}
+			continue
+		}
+		report.Checks[name] = CheckResult{OK: true}
+	}
+	return report
+}
+
+// Handler serves the readiness report: 200 when ready, 503 otherwise.
+func (rd *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := rd.Evaluate(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// CachedCheck wraps an expensive check (e.g. one that shells out to the Pulumi CLI)
+// so it runs at most once per ttl; callers in between get the last result.
+func CachedCheck(check Check, ttl time.Duration) Check {
+	var (
+		mu      sync.Mutex
+		last    error
+		checked time.Time
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return last
+		}
+		last = check(ctx)
+		checked = time.Now()
+		return last
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	rd := NewReadiness()
+	rulesErr := errors.New("no scaling rules loaded")
+	rd.Add("engine", func(context.Context) error { return nil })
+	rd.Add("rules", func(context.Context) error { return rulesErr })
+
+	w := httptest.NewRecorder()
+	rd.Handler()(w, httptest.NewRequest("GET", "/ready", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	var report ReadinessReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Ready || !report.Checks["engine"].OK || report.Checks["rules"].Message != rulesErr.Error() {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	rd.Add("rules", func(context.Context) error { return nil })
+	w = httptest.NewRecorder()
+	rd.Handler()(w, httptest.NewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestCachedCheck(t *testing.T) {
+	calls := 0
+	check := CachedCheck(func(context.Context) error {
+		calls++
+		return nil
+	}, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := check(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
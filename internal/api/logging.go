@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+// quietPaths are polled by probes and scrapers; their requests are only logged at debug level.
+var quietPaths = map[string]bool{"/health": true, "/ready": true, "/metrics": true}
+
+// RequestLogger logs every request when it completes and attaches a logger carrying the
+// request ID to its context, so handlers can log with zerolog.Ctx(r.Context()). Install it
+// after middleware.RequestID and middleware.RealIP.
+func RequestLogger(logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqLogger := logger.With().Str("requestId", middleware.GetReqID(r.Context())).Logger()
+			r = r.WithContext(reqLogger.WithContext(r.Context()))
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				var ev *zerolog.Event
+				switch {
+				case status >= 500:
+					ev = reqLogger.Error()
+				case status >= 400:
+					ev = reqLogger.Warn()
+				case quietPaths[r.URL.Path]:
+					ev = reqLogger.Debug()
+				default:
+					ev = reqLogger.Info()
+				}
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					ev = ev.Str("route", rctx.RoutePattern())
+				}
+				ev.Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Int("status", status).
+					Int("bytes", ww.BytesWritten()).
+					Dur("duration", time.Since(start)).
+					Str("remote", r.RemoteAddr).
+					Msg("HTTP request")
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.InfoLevel)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(RequestLogger(logger))
+	r.Post("/webhook/{pool}/delta", func(w http.ResponseWriter, r *http.Request) {
+		zerolog.Ctx(r.Context()).Info().Msg("Queued intent")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook/workers/delta", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	// The handler's line, the webhook request and the 404; /health is only logged at debug level.
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3:\n%s", len(lines), buf.String())
+	}
+	handler, request, missing := lines[0], lines[1], lines[2]
+	if handler["requestId"] == "" || handler["requestId"] != request["requestId"] {
+		t.Errorf("handler requestId = %v, request requestId = %v; want the same ID", handler["requestId"], request["requestId"])
+	}
+	if request["route"] != "/webhook/{pool}/delta" || request["status"] != 202.0 || request["bytes"] != 6.0 || request["method"] != "POST" {
+		t.Errorf("request line = %v", request)
+	}
+	if missing["level"] != "warn" || missing["status"] != 404.0 {
+		t.Errorf("404 line = %v, want a warning", missing)
+	}
+}
@@ -1,11 +1,30 @@
 package api
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
 )
 
-// AuthMiddleware enforces Bearer Token authentication.
+// RequesterMiddleware records the client address, as "addr:<address>", as the requester of
+// intents queued by the request. Every caller shares the same bearer token, so the address is
+// all that is known about the sender and must not be mistaken for an identity. Install it after
+// middleware.RealIP so proxied clients are identified.
+func RequesterMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if webhooks.RequesterFromContext(ctx) == "" {
+			ctx = webhooks.WithRequester(ctx, "addr:"+r.RemoteAddr)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AuthMiddleware enforces Bearer Token authentication. Basic auth with the token as the password is
+// accepted too, for senders like AWS SNS that can only put credentials in the URL.
+// An empty expectedToken rejects every request.
 func AuthMiddleware(expectedToken string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -15,14 +34,18 @@ func AuthMiddleware(expectedToken string) func(http.Handler) http.Handler {
 				return
 			}
 
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
+			token, ok := "", false
+			if _, password, basic := r.BasicAuth(); basic {
+				token, ok = password, true
+			} else if parts := strings.Split(authHeader, " "); len(parts) == 2 && parts[0] == "Bearer" {
+				token, ok = parts[1], true
+			}
+			if !ok {
 				http.Error(w, "Unauthorized: Invalid Authorization header format", http.StatusUnauthorized)
 				return
 			}
 
-			token := parts[1]
-			if token != expectedToken {
+			if expectedToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) != 1 {
 				http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
 				return
 			}
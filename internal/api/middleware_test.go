@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestRequesterMiddleware(t *testing.T) {
+	var got string
+	h := middleware.RealIP(RequesterMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = webhooks.RequesterFromContext(r.Context())
+	})))
+
+	req := httptest.NewRequest("POST", "/webhook/workers/delta", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.7")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "addr:10.0.0.7" {
+		t.Errorf("requester = %q, want the labelled client address", got)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		header   string
+		want     int
+	}{
+		{name: "valid token", expected: "s3cret", header: "Bearer s3cret", want: http.StatusOK},
+		{name: "wrong token", expected: "s3cret", header: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "missing header", expected: "s3cret", want: http.StatusUnauthorized},
+		{name: "wrong scheme", expected: "s3cret", header: "Token s3cret", want: http.StatusUnauthorized},
+		{name: "basic auth password", expected: "s3cret", header: "Basic " + base64.StdEncoding.EncodeToString([]byte("sns:s3cret")), want: http.StatusOK},
+		{name: "wrong basic auth password", expected: "s3cret", header: "Basic " + base64.StdEncoding.EncodeToString([]byte("s3cret:nope")), want: http.StatusUnauthorized},
+		{name: "empty expected token", expected: "", header: "Bearer ", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := AuthMiddleware(tt.expected)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest("POST", "/admin/reload", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package audit keeps an append-only JSONL log of every scaling decision.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+// Record is one processed intent: who asked for what, and what the engine did about it.
+// Guardrail clamps, cooldown skips and the apply outcome, including the stack's update
+// version, are in Job. For secret pools the requested value is left out and Secret is set.
+type Record struct {
+	Time      time.Time             `json:"time"`
+	Stack     string                `json:"stack"`
+	Pool      string                `json:"pool"`
+	Source    string                `json:"source,omitempty"`
+	Reason    string                `json:"reason,omitempty"`
+	Requester string                `json:"requester,omitempty"`
+	RequestID string                `json:"requestId,omitempty"`
+	Action    webhooks.IntentAction `json:"action"`
+	Value     float64               `json:"value"`
+	Text      string                `json:"text,omitempty"`
+	Secret    bool                  `json:"secret,omitempty"`
+	DryRun    bool                  `json:"dryRun,omitempty"`
+	Job       autoscaler.JobResult  `json:"job"`
+}
+
+// Query selects records. Zero fields match everything.
+type Query struct {
+	Stack string
+	Pool  string
+	Since time.Time
+
+	// Limit keeps only the most recent matching records.
+	Limit int
+}
+
+func (q Query) matches(rec Record) bool {
+	return (q.Stack == "" || rec.Stack == q.Stack) &&
+		(q.Pool == "" || rec.Pool == q.Pool) &&
+		!rec.Time.Before(q.Since)
+}
+
+// Log appends records to a JSONL file. Records are only ever appended, never rewritten.
+type Log struct {
+	Path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open opens the log at path for appending, creating it if needed.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{Path: path, f: f}, nil
+}
+
+// OpenReadOnly returns the log at path for queries only. Unlike Open it doesn't create
+// the file, and Append fails.
+func OpenReadOnly(path string) *Log {
+	return &Log{Path: path}
+}
+
+// errReadOnly is returned by Append on a log from OpenReadOnly.
+var errReadOnly = errors.New("audit log is open read-only")
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// Append writes rec as a single line and syncs it to disk.
+func (l *Log) Append(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return errReadOnly
+	}
+	if _, err := l.f.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return l.f.Sync()
+}
+
+// Observer returns an autoscaler.JobObserver that appends every job of the stack's engine.
+// Write failures are logged; they never block scaling.
+func (l *Log) Observer(stack string) autoscaler.JobObserver {
+	return func(intent webhooks.ScalingIntent, rule autoscaler.ScalingRule, result autoscaler.JobResult) {
+		rec := Record{
+			Time:      result.FinishedAt,
+			Stack:     stack,
+			Pool:      intent.TargetPool,
+			Source:    intent.Source,
+			Reason:    intent.Reason,
+			Requester: intent.Requester,
+			RequestID: intent.RequestID,
+			Action:    intent.Action,
+			Value:     intent.Value,
+			Text:      intent.Text,
+			DryRun:    intent.DryRun,
+			Job:       result,
+		}
+		if rule.Secret {
+			rec.Value, rec.Text, rec.Job.Requested = 0, "", 0
+			rec.Secret = true
+		}
+		if err := l.Append(rec); err != nil {
+			log.Error().Err(err).Str("pool", intent.TargetPool).Msg("Failed to record scaling decision in the audit log")
+		}
+	}
+}
+
+// Query returns the matching records, oldest first.
+func (l *Log) Query(q Query) ([]Record, error) {
+	f, err := os.Open(l.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", l.Path, line, err)
+		}
+		if q.matches(rec) {
+			out = append(out, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[len(out)-q.Limit:]
+	}
+	return out, nil
+}
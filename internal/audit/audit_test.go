@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestLogAppendAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	observe := l.Observer("dev")
+	observe(webhooks.ScalingIntent{
+		TargetPool: "workers",
+		Action:     webhooks.ActionSet,
+		Value:      50,
+		Source:     "cloudwatch",
+		Reason:     "CPU > 80%",
+		Requester:  "addr:10.0.0.1",
+		RequestID:  "host/abc-000001",
+	}, autoscaler.ScalingRule{}, autoscaler.JobResult{
+		Pool:          "workers",
+		Previous:      autoscaler.IntValue(3),
+		Target:        autoscaler.IntValue(10),
+		Status:        autoscaler.JobSucceeded,
+		Clamped:       autoscaler.ClampMax,
+		Requested:     50,
+		Attempts:      1,
+		UpdateVersion: 42,
+		FinishedAt:    base,
+	})
+	observe(webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionDelta, Value: 1}, autoscaler.ScalingRule{}, autoscaler.JobResult{
+		Pool:          "workers",
+		Status:        autoscaler.JobSkipped,
+		Skipped:       autoscaler.SkipCooldown,
+		CooldownUntil: base.Add(time.Minute),
+		FinishedAt:    base.Add(30 * time.Second),
+	})
+	l.Observer("prod")(webhooks.ScalingIntent{TargetPool: "api"}, autoscaler.ScalingRule{}, autoscaler.JobResult{
+		Pool:       "api",
+		Status:     autoscaler.JobSkipped,
+		Skipped:    autoscaler.SkipNoRule,
+		FinishedAt: base.Add(time.Hour),
+	})
+
+	all, err := l.Query(Query{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Query() = %d records, %v; want 3", len(all), err)
+	}
+	first := all[0]
+	if first.Stack != "dev" || first.Requester != "addr:10.0.0.1" || first.RequestID != "host/abc-000001" || first.Reason != "CPU > 80%" {
+		t.Errorf("intent fields not recorded: %+v", first)
+	}
+	if first.Job.Clamped != autoscaler.ClampMax || first.Job.Requested != 50 || first.Job.UpdateVersion != 42 {
+		t.Errorf("job fields not recorded: %+v", first.Job)
+	}
+	if !first.Job.Previous.Equal(autoscaler.IntValue(3)) || !first.Job.Target.Equal(autoscaler.IntValue(10)) {
+		t.Errorf("values = %v -> %v, want 3 -> 10", first.Job.Previous, first.Job.Target)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"by stack", Query{Stack: "dev"}, 2},
+		{"by pool", Query{Pool: "api"}, 1},
+		{"since", Query{Since: base.Add(time.Second)}, 2},
+		{"limit keeps the newest", Query{Stack: "dev", Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Query(tt.query)
+			if err != nil || len(got) != tt.want {
+				t.Fatalf("Query(%+v) = %d records, %v; want %d", tt.query, len(got), err, tt.want)
+			}
+		})
+	}
+	if got, _ := l.Query(Query{Stack: "dev", Limit: 1}); got[0].Job.Skipped != autoscaler.SkipCooldown {
+		t.Errorf("limited query returned %+v, want the cooldown skip", got[0].Job)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func TestLogKeepsValueTypes(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+
+	l.Observer("dev")(webhooks.ScalingIntent{TargetPool: "gpu"}, autoscaler.ScalingRule{}, autoscaler.JobResult{
+		Pool:     "gpu",
+		Previous: autoscaler.FloatValue(2),
+		Target:   autoscaler.FloatValue(2.5),
+		Status:   autoscaler.JobSucceeded,
+		Derived:  map[string]autoscaler.Value{"replicas": autoscaler.IntValue(4)},
+	})
+
+	records, err := l.Query(Query{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Query() = %d records, %v; want 1", len(records), err)
+	}
+	job := records[0].Job
+	// A whole float must not come back as an int.
+	if job.Previous.Type != autoscaler.ValueFloat || job.Previous.Number != 2 || job.Target.Type != autoscaler.ValueFloat {
+		t.Errorf("values = %+v -> %+v, want floats 2 -> 2.5", job.Previous, job.Target)
+	}
+	if job.Derived["replicas"].Type != autoscaler.ValueInt {
+		t.Errorf("derived replicas = %+v, want an int", job.Derived["replicas"])
+	}
+}
+
+func TestLogAppendsAcrossOpens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i := 0; i < 2; i++ {
+		l, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		if err := l.Append(Record{Pool: "workers", Time: time.Now()}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		l.Close()
+	}
+	l := &Log{Path: path}
+	if got, err := l.Query(Query{}); err != nil || len(got) != 2 {
+		t.Errorf("Query() = %d records, %v; want both appends kept", len(got), err)
+	}
+}
+
+func TestLogRedactsSecretPools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+
+	rule := autoscaler.ScalingRule{PoolName: "db", Secret: true}
+	target := autoscaler.IntValue(8000)
+	target.Secret = true
+	l.Observer("dev")(webhooks.ScalingIntent{TargetPool: "db", Action: webhooks.ActionSet, Value: 98765, Text: "s3cret-size"}, rule, autoscaler.JobResult{
+		Pool:      "db",
+		Target:    target,
+		Status:    autoscaler.JobSucceeded,
+		Clamped:   autoscaler.ClampMax,
+		Requested: 98765,
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"98765", "8000", "s3cret-size"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("audit record contains the secret value %s: %s", secret, data)
+		}
+	}
+	records, err := l.Query(Query{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Query() = %d records, %v; want 1", len(records), err)
+	}
+	if rec := records[0]; !rec.Secret || rec.Job.Clamped != autoscaler.ClampMax {
+		t.Errorf("record = %+v, want it marked secret and still clamped", rec)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := OpenReadOnly(path)
+	defer l.Close()
+
+	records, err := l.Query(Query{Pool: "workers"})
+	if err != nil || len(records) != 0 {
+		t.Errorf("Query() = %v, %v; want no records", records, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("query created the log file (stat err = %v)", err)
+	}
+	if err := l.Append(Record{Pool: "workers"}); err == nil {
+		t.Error("Append() on a read-only log succeeded")
+	}
+}
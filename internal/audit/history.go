@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+// HistoryEntry is one change to a pool: a decision from the audit log, the stack update
+// it ran, or both. Updates run without an audit log only carry what their message records.
+type HistoryEntry struct {
+	Time            time.Time             `json:"time"`
+	Stack           string                `json:"stack,omitempty"`
+	Pool            string                `json:"pool"`
+	Previous        autoscaler.Value      `json:"previous"`
+	Target          autoscaler.Value      `json:"target"`
+	Status          autoscaler.JobStatus  `json:"status,omitempty"`
+	Source          string                `json:"source,omitempty"`
+	Reason          string                `json:"reason,omitempty"`
+	Requester       string                `json:"requester,omitempty"`
+	Clamped         autoscaler.ClampBound `json:"clamped,omitempty"`
+	Requested       float64               `json:"requested,omitempty"`
+	Error           string                `json:"error,omitempty"`
+	DurationSeconds float64               `json:"durationSeconds,omitempty"`
+	UpdateVersion   int                   `json:"updateVersion,omitempty"`
+	UpdateResult    string                `json:"updateResult,omitempty"`
+}
+
+// updateJoinSlack is how far outside a job's start and finish an update may start and still
+// be counted as one of its attempts, allowing for clock skew with the backend.
+const updateJoinSlack = 30 * time.Second
+
+// History merges a pool's decisions with the scaler's updates from stack's history,
+// newest first. Records and updates are joined on the update version. Failed ups leave no
+// version in the record, so a job that ran ups also claims the pool's updates that started
+// while it ran. Skipped decisions changed nothing and are left out; GET /audit lists them.
+// limit <= 0 returns everything.
+func History(stack string, records []Record, updates []autoscaler.ScalerUpdate, limit int) []HistoryEntry {
+	byVersion := make(map[int]autoscaler.ScalerUpdate, len(updates))
+	for _, u := range updates {
+		byVersion[u.Version] = u
+	}
+
+	var out []HistoryEntry
+	for _, rec := range records {
+		job := rec.Job
+		if job.Status == autoscaler.JobSkipped {
+			continue
+		}
+		entry := HistoryEntry{
+			Time:          job.StartedAt,
+			Stack:         rec.Stack,
+			Pool:          rec.Pool,
+			Previous:      job.Previous,
+			Target:        job.Target,
+			Status:        job.Status,
+			Source:        rec.Source,
+			Reason:        rec.Reason,
+			Requester:     rec.Requester,
+			Clamped:       job.Clamped,
+			Requested:     job.Requested,
+			Error:         job.Error,
+			UpdateVersion: job.UpdateVersion,
+		}
+		if entry.Time.IsZero() {
+			entry.Time = rec.Time
+		}
+		if !job.StartedAt.IsZero() && !job.FinishedAt.IsZero() {
+			entry.DurationSeconds = job.FinishedAt.Sub(job.StartedAt).Seconds()
+		}
+		if u, ok := byVersion[job.UpdateVersion]; ok && job.UpdateVersion > 0 {
+			entry.UpdateResult = u.Result
+			delete(byVersion, job.UpdateVersion)
+		}
+		if job.Attempts > 0 && !job.StartedAt.IsZero() {
+			end := job.FinishedAt
+			if end.IsZero() {
+				end = job.StartedAt
+			}
+			from, to := job.StartedAt.Add(-updateJoinSlack), end.Add(updateJoinSlack)
+			for _, u := range updates {
+				if _, ok := byVersion[u.Version]; !ok || u.Pool != rec.Pool || u.StartedAt.Before(from) || u.StartedAt.After(to) {
+					continue
+				}
+				// Report the job's last up when it has no successful one.
+				if job.UpdateVersion == 0 && u.Version > entry.UpdateVersion {
+					entry.UpdateVersion, entry.UpdateResult = u.Version, u.Result
+				}
+				delete(byVersion, u.Version)
+			}
+		}
+		out = append(out, entry)
+	}
+
+	// Updates the audit log doesn't know about, e.g. from before it was enabled.
+	for _, u := range updates {
+		if _, ok := byVersion[u.Version]; !ok {
+			continue
+		}
+		entry := HistoryEntry{
+			Time:          u.StartedAt,
+			Stack:         stack,
+			Pool:          u.Pool,
+			Previous:      u.Previous,
+			Target:        u.Target,
+			Source:        u.Source,
+			UpdateVersion: u.Version,
+			UpdateResult:  u.Result,
+		}
+		if !u.FinishedAt.IsZero() {
+			entry.DurationSeconds = u.FinishedAt.Sub(u.StartedAt).Seconds()
+		}
+		out = append(out, entry)
+	}
+
+	slices.SortStableFunc(out, func(a, b HistoryEntry) int {
+		return cmp.Compare(b.Time.UnixNano(), a.Time.UnixNano())
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+)
+
+func TestHistory(t *testing.T) {
+	base := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Stack: "dev", Pool: "workers", Source: "cloudwatch", Reason: "CPU > 80%", Job: autoscaler.JobResult{
+			Previous: autoscaler.IntValue(20), Target: autoscaler.IntValue(40), Status: autoscaler.JobSucceeded,
+			UpdateVersion: 7, StartedAt: base, FinishedAt: base.Add(90 * time.Second),
+		}},
+		{Stack: "dev", Pool: "workers", Job: autoscaler.JobResult{
+			Status: autoscaler.JobSkipped, Skipped: autoscaler.SkipCooldown, StartedAt: base.Add(time.Minute),
+		}},
+	}
+	updates := []autoscaler.ScalerUpdate{
+		{Version: 7, Pool: "workers", Result: "succeeded", StartedAt: base},
+		{Version: 5, Pool: "workers", Previous: autoscaler.IntValue(10), Target: autoscaler.IntValue(20), Source: "prometheus",
+			Result: "failed", StartedAt: base.Add(-time.Hour), FinishedAt: base.Add(-time.Hour + time.Minute)},
+	}
+
+	got := History("dev", records, updates, 0)
+	if len(got) != 2 {
+		t.Fatalf("History() = %d entries, want the decision joined with its update plus the older update", len(got))
+	}
+	joined := got[0]
+	if joined.UpdateVersion != 7 || joined.UpdateResult != "succeeded" || joined.Reason != "CPU > 80%" || joined.DurationSeconds != 90 {
+		t.Errorf("joined entry = %+v", joined)
+	}
+	older := got[1]
+	if older.UpdateVersion != 5 || older.Source != "prometheus" || older.Stack != "dev" || !older.Target.Equal(autoscaler.IntValue(20)) || older.DurationSeconds != 60 {
+		t.Errorf("update-only entry = %+v", older)
+	}
+
+	if limited := History("dev", records, updates, 1); len(limited) != 1 || limited[0].UpdateVersion != 7 {
+		t.Errorf("History(limit 1) = %+v, want the newest entry", limited)
+	}
+}
+
+func TestHistoryJoinsFailedUpdates(t *testing.T) {
+	base := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)
+	records := []Record{
+		// Failed ups leave no update version in the record.
+		{Stack: "dev", Pool: "workers", Source: "cloudwatch", Job: autoscaler.JobResult{
+			Target: autoscaler.IntValue(40), Status: autoscaler.JobFailed, Attempts: 2, Error: "up failed",
+			StartedAt: base, FinishedAt: base.Add(3 * time.Minute),
+		}},
+		// Retried after a failed up, then succeeded.
+		{Stack: "dev", Pool: "workers", Source: "cloudwatch", Job: autoscaler.JobResult{
+			Target: autoscaler.IntValue(30), Status: autoscaler.JobSucceeded, Attempts: 2, UpdateVersion: 12,
+			StartedAt: base.Add(time.Hour), FinishedAt: base.Add(time.Hour + 2*time.Minute),
+		}},
+	}
+	updates := []autoscaler.ScalerUpdate{
+		{Version: 12, Pool: "workers", Result: "succeeded", StartedAt: base.Add(time.Hour + time.Minute)},
+		{Version: 11, Pool: "workers", Result: "failed", StartedAt: base.Add(time.Hour)},
+		{Version: 10, Pool: "workers", Result: "failed", StartedAt: base.Add(2 * time.Minute)},
+		{Version: 9, Pool: "workers", Result: "failed", StartedAt: base.Add(time.Second)},
+		// Another pool's update in the same window stays separate.
+		{Version: 8, Pool: "api", Result: "failed", StartedAt: base.Add(time.Second)},
+		// Long before the job: not one of its attempts.
+		{Version: 4, Pool: "workers", Result: "failed", StartedAt: base.Add(-time.Hour)},
+	}
+
+	got := History("dev", records, updates, 0)
+	byVersion := map[int]HistoryEntry{}
+	var versions []int
+	for _, e := range got {
+		byVersion[e.UpdateVersion] = e
+		versions = append(versions, e.UpdateVersion)
+	}
+	// 9 and 11 are the jobs' earlier attempts; 8 is another pool's and 4 predates the jobs.
+	if fmt.Sprint(versions) != "[12 8 10 4]" {
+		t.Fatalf("History() versions = %v, want [12 8 10 4]", versions)
+	}
+	if e := byVersion[12]; e.UpdateResult != "succeeded" || e.Status != autoscaler.JobSucceeded {
+		t.Errorf("retried job = %+v, want joined with its successful update 12", e)
+	}
+	if e := byVersion[10]; e.UpdateResult != "failed" || e.Error != "up failed" || e.Source != "cloudwatch" {
+		t.Errorf("failed job = %+v, want joined with its last failed update 10", e)
+	}
+	if e := byVersion[8]; e.Pool != "api" || e.Status != "" {
+		t.Errorf("other pool's update = %+v, want it listed on its own", e)
+	}
+}
@@ -2,91 +2,259 @@ package autoscaler
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/rs/zerolog/log"
 )
 
-// ConfigLoader is responsible for loading scaling rules from the Pulumi stack.
+// ConfigLoader is responsible for loading scaling rules from the Pulumi stack
+// and any additional RuleSources.
 type ConfigLoader struct {
 	StackName string
 	WorkDir   string
+
+	// Sources in precedence order, highest first. When several sources define the
+	// same pool, the highest-precedence definition replaces the others entirely;
+	// fields are never merged across sources.
+	Sources []RuleSource
+
+	// (Optional) Project that rule URNs must belong to. When empty it is read
+	// from the workspace's Pulumi.yaml if one exists.
+	Project string
+	project func(ctx context.Context) (string, error)
 }
 
-// NewConfigLoader creates a new ConfigLoader instance.
+// NewConfigLoader creates a new ConfigLoader instance that reads the "pulumiscale" stack output.
+// Add further sources with AddSource.
 func NewConfigLoader(stackName, workDir string) *ConfigLoader {
 	return &ConfigLoader{
 		StackName: stackName,
 		WorkDir:   workDir,
+		Sources:   []RuleSource{NewOutputSource(stackName, workDir)},
+		project:   localProject(workDir),
 	}
 }
 
-// LoadRules retrieves the stack outputs and parses the "pulumiscale" output into a map of ScalingRules.
-func (cl *ConfigLoader) LoadRules(ctx context.Context) (map[string]ScalingRule, error) {
-	// Initialize the stack (assuming workDir contains a valid Pulumi program)
-	// We use UpsertStack to get a handle to the stack, assuming it already exists.
-	// If it doesn't, we might need a different approach, but for a sidecar, the stack should exist.
-	s, err := auto.UpsertStackLocalSource(ctx, cl.StackName, cl.WorkDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load stack: %w", err)
+// NewConfigLoaderFor reads the "pulumiscale" output of the stack managed by sm, for stacks that
+// aren't opened from a local work directory (see NewGitStateManager). Set Project to enable the
+// URN project check.
+func NewConfigLoaderFor(sm *StateManager) *ConfigLoader {
+	return &ConfigLoader{
+		StackName: sm.StackName,
+		Sources:   []RuleSource{NewOutputSourceFor(sm)},
 	}
+}
 
-	// Get stack outputs
-	outputs, err := s.Outputs(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get stack outputs: %w", err)
-	}
+// AddSource adds a source that takes precedence over all sources added before it.
+func (cl *ConfigLoader) AddSource(src RuleSource) {
+	cl.Sources = append([]RuleSource{src}, cl.Sources...)
+}
 
-	// Look for "pulumiscale" output
-	val, ok := outputs["pulumiscale"]
-	if !ok {
-		return nil, fmt.Errorf("stack output 'pulumiscale' not found")
-	}
+// LoadRules loads every source and merges their rules by pool name.
+// Any source failing to load fails the whole load, so a reload never silently drops pools.
+// Contract violations from every source are collected and returned together as ValidationErrors.
+func (cl *ConfigLoader) LoadRules(ctx context.Context) (map[string]ScalingRule, error) {
+	rules := make(map[string]ScalingRule)
+	origin := make(map[string]string)
+	var invalid ValidationErrors
 
-	// The output value from Automation API is an auto.OutputValue.
-	// We need to marshal/unmarshal or type assert to get the structure.
-	// auto.OutputValue.Value is interface{}.
-	
-	// Marshaling the value to JSON and then unmarshaling into our struct is a robust way to handle map[string]interface{}.
-	data, err := json.Marshal(val.Value)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal pulumiscale output: %w", err)
+	// Apply lowest precedence first so higher sources overwrite.
+	for i := len(cl.Sources) - 1; i >= 0; i-- {
+		src := cl.Sources[i]
+		loaded, err := src.Load(ctx)
+		var verrs ValidationErrors
+		if errors.As(err, &verrs) {
+			invalid = append(invalid, verrs.withSource(src.Name())...)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.Name(), err)
+		}
+		for name, rule := range loaded {
+			if prev, ok := origin[name]; ok {
+				log.Info().Str("pool", name).Str("source", src.Name()).Str("overrides", prev).Msg("Pool defined by multiple rule sources")
+			}
+			rules[name] = rule
+			origin[name] = src.Name()
+		}
 	}
 
-	var rules map[string]ScalingRule
-	if err := json.Unmarshal(data, &rules); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal scaling rules: %w", err)
+	if len(invalid) == 0 && len(origin) == 0 {
+		names := make([]string, len(cl.Sources))
+		for i, src := range cl.Sources {
+			names[i] = src.Name()
+		}
+		return nil, fmt.Errorf("no scaling rules found in %s", strings.Join(names, ", "))
 	}
 
-	// Populate PoolName from the map key since it's ignored in JSON (`json:"-"`)
-	for name, rule := range rules {
-		rule.PoolName = name
-		if err := rule.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid rule for pool '%s': %w", name, err)
+	stackName, project := cl.urnScope(ctx)
+	for _, name := range slices.Sorted(maps.Keys(rules)) {
+		rule := rules[name]
+		if errs := rule.validate(stackName, project); len(errs) > 0 {
+			invalid = append(invalid, errs.withPrefix("/"+jsonPointerEscape(name)).withSource(origin[name])...)
 		}
-		rules[name] = rule
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("invalid scaling rules: %w", invalid)
 	}
 
 	return rules, nil
 }
 
-// Validate checks if the ScalingRule is valid.
+// urnScope returns the stack and project that rule URNs must belong to.
+// Either is empty when it can't be determined, which skips that check.
+func (cl *ConfigLoader) urnScope(ctx context.Context) (string, string) {
+	project := cl.Project
+	if project == "" && cl.project != nil {
+		name, err := cl.project(ctx)
+		if err != nil {
+			log.Debug().Err(err).Msg("Could not resolve project name; skipping URN project check")
+		}
+		project = name
+	}
+	return shortStackName(cl.StackName), project
+}
+
+// localProject reads the project name from Pulumi.yaml in workDir.
+func localProject(workDir string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		ws, err := auto.NewLocalWorkspace(ctx, auto.WorkDir(workDir))
+		if err != nil {
+			return "", err
+		}
+		proj, err := ws.ProjectSettings(ctx)
+		if err != nil {
+			return "", err
+		}
+		return string(proj.Name), nil
+	}
+}
+
+// Validate checks if the ScalingRule is valid and returns every problem found as
+// ValidationErrors, with pointers relative to the rule.
 func (r *ScalingRule) Validate() error {
-	if r.TargetURN == "" {
-		return fmt.Errorf("targetUrn is required")
+	if errs := r.validate("", ""); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate checks the rule and, when stackName or project are set, that its URNs belong to them.
+func (r *ScalingRule) validate(stackName, project string) ValidationErrors {
+	var errs ValidationErrors
+	add := func(ptr, format string, args ...any) {
+		errs = append(errs, FieldError{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
 	}
+	checkURNAt := func(ptr, urn string) {
+		if urn == "" {
+			add(ptr, "must not be empty")
+			return
+		}
+		if err := checkURN(urn, stackName, project); err != nil {
+			add(ptr, "%v", err)
+		}
+	}
+	checkURNs := func(ptr string, urns []string) {
+		for i, urn := range urns {
+			checkURNAt(fmt.Sprintf("%s/%d", ptr, i), urn)
+		}
+	}
+
+	if r.TargetURN == "" && len(r.TargetURNs) == 0 {
+		add("/targetUrn", "targetUrn is required")
+	}
+	if r.TargetURN != "" {
+		checkURNAt("/targetUrn", r.TargetURN)
+	}
+	checkURNs("/targetUrns", r.TargetURNs)
+	checkURNs("/alsoTarget", r.AlsoTarget)
 	if r.ConfigKey == "" {
-		return fmt.Errorf("configKey is required")
+		add("/configKey", "configKey is required")
+	}
+	switch r.Type {
+	case "", ValueInt, ValueFloat, ValueString:
+	default:
+		add("/type", "type must be one of %q, %q or %q", ValueInt, ValueFloat, ValueString)
+	}
+	if r.Type != ValueString && len(r.Allowed) > 0 {
+		add("/allowed", "allowed is only valid for type %q", ValueString)
+	}
+	if r.Type == ValueString && len(r.DerivedKeys) > 0 {
+		add("/derivedKeys", "derivedKeys require a numeric type")
+	}
+	seen := map[string]bool{r.ConfigKey: true}
+	for i, dk := range r.DerivedKeys {
+		ptr := fmt.Sprintf("/derivedKeys/%d", i)
+		if dk.ConfigKey == "" {
+			add(ptr+"/configKey", "configKey is required")
+		} else if seen[dk.ConfigKey] {
+			add(ptr+"/configKey", "duplicate config key %s", dk.ConfigKey)
+		}
+		seen[dk.ConfigKey] = true
+		if dk.Type != "" && dk.Type != ValueInt && dk.Type != ValueFloat {
+			add(ptr+"/type", "type must be %q or %q", ValueInt, ValueFloat)
+		}
+		if _, err := parseExpr(dk.Value); err != nil {
+			add(ptr+"/value", "%v", err)
+		}
 	}
 	if r.Min < 0 {
-		return fmt.Errorf("min must be non-negative")
+		add("/min", "min must be non-negative")
 	}
 	if r.Max < r.Min {
-		return fmt.Errorf("max must be greater than or equal to min")
+		add("/max", "max must be greater than or equal to min")
 	}
 	if r.CooldownSeconds < 0 {
-		return fmt.Errorf("cooldown must be non-negative")
+		add("/cooldown", "cooldown must be non-negative")
 	}
-	return nil
+	if r.PinnedAlertSeconds < 0 {
+		add("/pinnedAlert", "pinnedAlert must be non-negative")
+	}
+	switch r.OnFailure {
+	case "", FailureRollback, FailureLeave, FailureRetry:
+	default:
+		add("/onFailure", "onFailure must be one of %q, %q or %q", FailureRollback, FailureLeave, FailureRetry)
+	}
+	if r.FailureRetries < -1 {
+		add("/failureRetries", "failureRetries must be -1 (disabled) or non-negative")
+	}
+	if r.Retry.MaxRetries < -1 {
+		add("/retry/maxRetries", "must be -1 (disabled) or non-negative")
+	}
+	if r.Retry.BaseDelaySeconds < 0 {
+		add("/retry/baseDelay", "must be non-negative")
+	}
+	if r.Retry.MaxDelaySeconds < 0 {
+		add("/retry/maxDelay", "must be non-negative")
+	}
+	if r.Retry.Jitter < 0 || r.Retry.Jitter > 1 {
+		add("/retry/jitter", "retry.jitter must be between 0 and 1")
+	}
+	if cf := r.CurrentFrom; cf != nil {
+		switch {
+		case (cf.Property == "") == (cf.Output == ""):
+			add("/currentFrom", "exactly one of property or output is required")
+		case cf.Output != "" && cf.Resource != "":
+			add("/currentFrom/resource", "resource is only valid with property")
+		case cf.Resource != "":
+			checkURNAt("/currentFrom/resource", cf.Resource)
+		}
+	}
+	if r.Drift != nil {
+		if r.Drift.Property == "" {
+			add("/drift/property", "property is required")
+		}
+		switch r.Drift.Policy {
+		case "", DriftReport, DriftAdopt, DriftCorrect:
+		default:
+			add("/drift/policy", "policy must be one of %q, %q or %q", DriftReport, DriftAdopt, DriftCorrect)
+		}
+	}
+	return errs
 }
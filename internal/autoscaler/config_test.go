@@ -70,6 +70,68 @@ func TestScalingRule_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "target urns without target urn",
+			rule: ScalingRule{
+				TargetURNs: []string{"urn:pulumi:stack::project::type::name"},
+				ConfigKey:  "count",
+				Min:        1,
+				Max:        10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "derived key with invalid expression",
+			rule: ScalingRule{
+				TargetURN:   "urn:pulumi:stack::project::type::name",
+				ConfigKey:   "count",
+				DerivedKeys: []DerivedKey{{ConfigKey: "maxSize", Value: "desired +"}},
+				Min:         1,
+				Max:         10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "derived key duplicates config key",
+			rule: ScalingRule{
+				TargetURN:   "urn:pulumi:stack::project::type::name",
+				ConfigKey:   "count",
+				DerivedKeys: []DerivedKey{{ConfigKey: "count", Value: "desired"}},
+				Min:         1,
+				Max:         10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "current from output",
+			rule: ScalingRule{
+				TargetURN:   "urn:pulumi:stack::project::type::name",
+				ConfigKey:   "count",
+				CurrentFrom: &CurrentSource{Output: "workerCount"},
+				Max:         10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "current from property and output",
+			rule: ScalingRule{
+				TargetURN:   "urn:pulumi:stack::project::type::name",
+				ConfigKey:   "count",
+				CurrentFrom: &CurrentSource{Property: "desiredCapacity", Output: "workerCount"},
+				Max:         10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "drift without property",
+			rule: ScalingRule{
+				TargetURN: "urn:pulumi:stack::project::type::name",
+				ConfigKey: "count",
+				Drift:     &DriftConfig{Policy: DriftAdopt},
+				Max:       10,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
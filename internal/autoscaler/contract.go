@@ -0,0 +1,143 @@
+package autoscaler
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ContractSchema is the JSON Schema for the pulumiscale output contract.
+//
+//go:embed schema/pulumiscale.v1.json
+var ContractSchema []byte
+
+// ContractSchemaURL identifies the current contract version.
+const ContractSchemaURL = "https://github.com/rshade/pulumi-scale/schema/pulumiscale.v1.json"
+
+var contractSchema = jsonschema.MustCompileString(ContractSchemaURL, string(ContractSchema))
+
+// FieldError is a single contract violation located by a JSON pointer into the rules document.
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+	Source  string `json:"source,omitempty"`
+}
+
+func (fe FieldError) String() string {
+	ptr := fe.Pointer
+	if ptr == "" {
+		ptr = "/"
+	}
+	if fe.Source != "" {
+		return fmt.Sprintf("%s#%s: %s", fe.Source, ptr, fe.Message)
+	}
+	return fmt.Sprintf("%s: %s", ptr, fe.Message)
+}
+
+// ValidationErrors collects every contract violation instead of stopping at the first.
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, fe := range ve {
+		msgs[i] = fe.String()
+	}
+	if len(ve) == 1 {
+		return msgs[0]
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(ve), strings.Join(msgs, "; "))
+}
+
+// withPrefix returns the errors with prefix prepended to every pointer.
+func (ve ValidationErrors) withPrefix(prefix string) ValidationErrors {
+	out := make(ValidationErrors, len(ve))
+	for i, fe := range ve {
+		fe.Pointer = prefix + fe.Pointer
+		out[i] = fe
+	}
+	return out
+}
+
+// withSource returns the errors tagged with the rule source they came from.
+func (ve ValidationErrors) withSource(source string) ValidationErrors {
+	out := make(ValidationErrors, len(ve))
+	for i, fe := range ve {
+		fe.Source = source
+		out[i] = fe
+	}
+	return out
+}
+
+// validateSchema checks a raw rules document against ContractSchema and returns
+// every leaf violation sorted by pointer.
+func validateSchema(data []byte) ValidationErrors {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return ValidationErrors{{Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	err := contractSchema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return ValidationErrors{{Message: err.Error()}}
+	}
+
+	var errs ValidationErrors
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			msg := e.Message
+			if strings.HasSuffix(e.AbsoluteKeywordLocation, "#/$defs/urn/pattern") {
+				msg = "is not a Pulumi URN (urn:pulumi:<stack>::<project>::<type>::<name>)"
+			}
+			errs = append(errs, FieldError{Pointer: e.InstanceLocation, Message: msg})
+			return
+		}
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(verr)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Pointer < errs[j].Pointer })
+	return errs
+}
+
+// checkURN verifies that urn belongs to the given stack and project.
+// Empty stack or project skip the corresponding comparison.
+func checkURN(urn, stack, project string) error {
+	parts := strings.SplitN(urn, "::", 4)
+	if len(parts) != 4 || !strings.HasPrefix(parts[0], "urn:pulumi:") {
+		return fmt.Errorf("%q is not a Pulumi URN (urn:pulumi:<stack>::<project>::<type>::<name>)", urn)
+	}
+	urnStack := strings.TrimPrefix(parts[0], "urn:pulumi:")
+	if stack != "" && urnStack != stack {
+		return fmt.Errorf("URN is for stack %q, expected %q", urnStack, stack)
+	}
+	if project != "" && parts[1] != project {
+		return fmt.Errorf("URN is for project %q, expected %q", parts[1], project)
+	}
+	return nil
+}
+
+// shortStackName strips the organization and project from a fully qualified stack name,
+// since URNs only contain the bare stack name.
+func shortStackName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// jsonPointerEscape escapes a JSON pointer reference token (RFC 6901).
+func jsonPointerEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
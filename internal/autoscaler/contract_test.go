@@ -0,0 +1,119 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testURN = "urn:pulumi:dev::app::aws:autoscaling/group:Group::workers"
+
+func TestParseRulesSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		pointers []string
+	}{
+		{
+			name: "valid with $schema",
+			doc:  `{"$schema": "` + ContractSchemaURL + `", "workers": {"targetUrn": "` + testURN + `", "configKey": "count", "max": 3}}`,
+		},
+		{
+			name:     "unknown fields",
+			doc:      `{"workers": {"targetURN": "` + testURN + `", "configKey": "count", "cooldownSeconds": 60}}`,
+			pointers: []string{"/workers"},
+		},
+		{
+			name:     "wrong types",
+			doc:      `{"workers": {"targetUrn": "` + testURN + `", "configKey": "count", "max": "10", "cooldown": 1.5}}`,
+			pointers: []string{"/workers/cooldown", "/workers/max"},
+		},
+		{
+			name:     "bad urns",
+			doc:      `{"workers": {"targetUrn": "workers", "configKey": "count", "alsoTarget": ["` + testURN + `", "alarm"]}}`,
+			pointers: []string{"/workers/alsoTarget/1", "/workers/targetUrn"},
+		},
+		{
+			name:     "errors across pools",
+			doc:      `{"a": {"configKey": "count", "onFailure": "panic"}, "b": {"targetUrn": "` + testURN + `"}}`,
+			pointers: []string{"/a/onFailure", "/b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := parseRules([]byte(tt.doc))
+			if len(tt.pointers) == 0 {
+				if err != nil {
+					t.Fatalf("parseRules() error = %v", err)
+				}
+				if _, ok := rules["$schema"]; ok {
+					t.Error("$schema was decoded as a pool")
+				}
+				return
+			}
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+			var got []string
+			for _, fe := range verrs {
+				if len(got) == 0 || got[len(got)-1] != fe.Pointer {
+					got = append(got, fe.Pointer)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.pointers, ",") {
+				t.Errorf("pointers = %v, want %v (%v)", got, tt.pointers, verrs)
+			}
+		})
+	}
+}
+
+func TestValidateCollectsAllErrors(t *testing.T) {
+	rule := ScalingRule{
+		Min:             5,
+		Max:             1,
+		CooldownSeconds: -1,
+		DerivedKeys:     []DerivedKey{{ConfigKey: "maxSize", Value: "desired +"}},
+	}
+	var verrs ValidationErrors
+	if !errors.As(rule.Validate(), &verrs) {
+		t.Fatal("expected ValidationErrors")
+	}
+	want := []string{"/targetUrn", "/configKey", "/derivedKeys/0/value", "/max", "/cooldown"}
+	if len(verrs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(verrs), len(want), verrs)
+	}
+	for i, ptr := range want {
+		if verrs[i].Pointer != ptr {
+			t.Errorf("error %d pointer = %s, want %s", i, verrs[i].Pointer, ptr)
+		}
+	}
+}
+
+func TestConfigLoaderURNScope(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	doc := `{"workers": {"targetUrn": "` + testURN + `", "configKey": "count", "max": 3,
+		"alsoTarget": ["urn:pulumi:prod::app::aws:cloudwatch/metricAlarm:MetricAlarm::alarm"]}}`
+	if err := os.WriteFile(file, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &ConfigLoader{StackName: "org/app/dev", Project: "app", Sources: []RuleSource{&FileSource{Path: file}}}
+	_, err := loader.LoadRules(context.Background())
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	if len(verrs) != 1 || verrs[0].Pointer != "/workers/alsoTarget/0" || verrs[0].Source != "file:"+file {
+		t.Errorf("unexpected errors: %v", verrs)
+	}
+
+	loader.Project = "other"
+	_, err = loader.LoadRules(context.Background())
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Errorf("expected project mismatch on both URNs, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/rs/zerolog/log"
+)
+
+// DriftStatus is the result of comparing one pool's config value with its live resource state.
+type DriftStatus struct {
+	Pool      string      `json:"pool"`
+	Config    Value       `json:"config"`
+	Live      Value       `json:"live"`
+	Drifted   bool        `json:"drifted"`
+	Policy    DriftPolicy `json:"policy"`
+	Action    string      `json:"action,omitempty"` // "adopted" or "corrected" when the policy acted
+	Error     string      `json:"error,omitempty"`
+	CheckedAt time.Time   `json:"checkedAt"`
+}
+
+// DriftChecker periodically compares every pool that has a Drift config with the
+// live state of its target resource and applies the pool's DriftPolicy.
+type DriftChecker struct {
+	Engine   *Engine
+	Interval time.Duration
+
+	// OnCheck, if set, is called with every status after it is recorded.
+	OnCheck func(DriftStatus)
+
+	mu       sync.Mutex
+	statuses map[string]DriftStatus
+}
+
+func NewDriftChecker(engine *Engine, interval time.Duration) *DriftChecker {
+	return &DriftChecker{
+		Engine:   engine,
+		Interval: interval,
+		statuses: make(map[string]DriftStatus),
+	}
+}
+
+// Statuses returns the latest status of every checked pool, sorted by pool name.
+func (d *DriftChecker) Statuses() []DriftStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DriftStatus, 0, len(d.statuses))
+	for _, name := range slices.Sorted(maps.Keys(d.statuses)) {
+		out = append(out, d.statuses[name])
+	}
+	return out
+}
+
+// Check compares every pool with a Drift config and returns the new statuses.
+func (d *DriftChecker) Check(ctx context.Context) []DriftStatus {
+	rules := d.Engine.RulesSnapshot()
+	var checked []DriftStatus
+	for _, name := range slices.Sorted(maps.Keys(rules)) {
+		rule := rules[name]
+		if rule.Drift == nil {
+			continue
+		}
+		st := d.checkPool(ctx, rule)
+		checked = append(checked, st)
+		if d.OnCheck != nil {
+			d.OnCheck(st)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statuses = make(map[string]DriftStatus, len(checked))
+	for _, st := range checked {
+		d.statuses[st.Pool] = st
+	}
+	return checked
+}
+
+// checkPool reads the live value without the engine lock, so a slow refresh doesn't hold up
+// scaling jobs, then takes it to compare and act, so a correction or adoption never
+// interleaves with a scaling job.
+func (d *DriftChecker) checkPool(ctx context.Context, rule ScalingRule) DriftStatus {
+	e := d.Engine
+	logger := log.With().Str("pool", rule.PoolName).Logger()
+	ctx = logger.WithContext(ctx)
+
+	st := DriftStatus{Pool: rule.PoolName, Policy: rule.Drift.Policy, CheckedAt: time.Now()}
+	if st.Policy == "" {
+		st.Policy = DriftReport
+	}
+	fail := func(err error) DriftStatus {
+		logger.Warn().Err(err).Msg("Drift check failed")
+		st.Error = err.Error()
+		return st
+	}
+
+	before, err := e.State.ConfigValue(ctx, rule)
+	if err != nil {
+		return fail(err)
+	}
+	live, err := e.State.LiveValue(ctx, rule, rule.Drift.Property, rule.Drift.Refresh)
+	if err != nil {
+		return fail(err)
+	}
+	st.Live = live
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	current, err := e.State.ConfigValue(ctx, rule)
+	if err != nil {
+		return fail(err)
+	}
+	st.Config = current
+	if !current.Equal(before) {
+		// A job scaled the pool while the live value was read, so the two can't be compared.
+		logger.Debug().Stringer("config", current).Msg("Pool scaled during drift check; checking again next time")
+		return st
+	}
+
+	if live.Equal(current) {
+		e.setDrifted(rule.PoolName, false)
+		return st
+	}
+	st.Drifted = true
+	e.setDrifted(rule.PoolName, true)
+	logger.Warn().
+		Stringer("config", current).
+		Stringer("live", live).
+		Str("policy", string(st.Policy)).
+		Msg("Drift detected")
+
+	switch st.Policy {
+	case DriftAdopt:
+		if err := e.State.Adopt(ctx, rule, live); err != nil {
+			return fail(err)
+		}
+		st.Action = "adopted"
+	case DriftCorrect:
+		if _, err := e.State.Apply(withSource(ctx, "drift"), rule, current); err != nil {
+			return fail(err)
+		}
+		st.Action = "corrected"
+	default:
+		return st
+	}
+	e.setDrifted(rule.PoolName, false)
+	e.forgetCurrent(rule.PoolName)
+	logger.Info().Str("action", st.Action).Msg("Drift resolved")
+	return st
+}
+
+// Run checks for drift every Interval until ctx is cancelled. A zero Interval disables it.
+func (d *DriftChecker) Run(ctx context.Context) {
+	if d.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Check(ctx)
+		}
+	}
+}
+
+// LiveValue reads a property of the rule's first target resource from the stack state,
+// optionally refreshing the targets first so the state reflects changes made outside Pulumi.
+func (sm *StateManager) LiveValue(ctx context.Context, rule ScalingRule, property string, refresh bool) (Value, error) {
+	targets := rule.targets()
+	if len(targets) == 0 {
+		return Value{}, fmt.Errorf("pool %s has no target resource", rule.PoolName)
+	}
+	if refresh {
+		s, err := sm.stack(ctx)
+		if err != nil {
+			return Value{}, err
+		}
+		err = sm.retryOnConcurrency(ctx, rule.Retry, func() error {
+			_, err := s.Refresh(ctx, optrefresh.Target(targets))
+			return err
+		})
+		if err != nil {
+			return Value{}, fmt.Errorf("refresh failed: %w", err)
+		}
+	}
+
+	return sm.resourceValue(ctx, rule, targets[0], property)
+}
+
+// resourceValue reads a property of a resource from the stack state, parsed as the rule's type.
+func (sm *StateManager) resourceValue(ctx context.Context, rule ScalingRule, urn, property string) (Value, error) {
+	resources, err := sm.Resources(ctx)
+	if err != nil {
+		return Value{}, err
+	}
+	for _, res := range resources {
+		if string(res.URN) != urn {
+			continue
+		}
+		raw, err := lookupProperty(res.Outputs, property)
+		if err != nil {
+			return Value{}, fmt.Errorf("%s: %w", urn, err)
+		}
+		v, err := propertyValue(rule.valueType(), raw)
+		v.Secret = rule.Secret
+		return v, err
+	}
+	return Value{}, fmt.Errorf("resource %s does not exist in the stack", urn)
+}
+
+// outputValue reads a stack output, where path is the output name optionally followed by
+// a dot-separated path into its value. The value is secret if the output or the rule is.
+func (sm *StateManager) outputValue(ctx context.Context, rule ScalingRule, path string) (Value, error) {
+	s, err := sm.stack(ctx)
+	if err != nil {
+		return Value{}, err
+	}
+	outputs, err := s.Outputs(ctx)
+	if err != nil {
+		return Value{}, fmt.Errorf("failed to get stack outputs: %w", err)
+	}
+	values := make(map[string]any, len(outputs))
+	for name, out := range outputs {
+		values[name] = out.Value
+	}
+	raw, err := lookupProperty(values, path)
+	if err != nil {
+		return Value{}, fmt.Errorf("stack output: %w", err)
+	}
+	name, _, _ := strings.Cut(path, ".")
+	v, err := propertyValue(rule.valueType(), raw)
+	v.Secret = outputs[name].Secret || rule.Secret
+	return v, err
+}
+
+// Adopt writes a live value, and the derived keys that follow it, to the stack config without an up.
+func (sm *StateManager) Adopt(ctx context.Context, rule ScalingRule, live Value) error {
+	s, err := sm.stack(ctx)
+	if err != nil {
+		return err
+	}
+	current, raw, err := getValue(ctx, s, rule)
+	if err != nil {
+		return err
+	}
+	return persistBaseline(ctx, s, rule, live, current, raw.Secret)
+}
+
+// secretSig marks a secret value in exported stack state.
+const secretSig = "4dabf18193072939515e22adb298388d"
+
+// lookupProperty follows a dot-separated path through a resource's outputs.
+func lookupProperty(outputs map[string]any, path string) (any, error) {
+	var cur any = outputs
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("property %s not found", path)
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("property %s not found", path)
+		}
+	}
+	if obj, ok := cur.(map[string]any); ok && obj[secretSig] != nil {
+		return nil, fmt.Errorf("property %s is a secret", path)
+	}
+	return cur, nil
+}
+
+// propertyValue converts a decoded state property to a Value of type t.
+func propertyValue(t ValueType, raw any) (Value, error) {
+	switch v := raw.(type) {
+	case float64:
+		if t == ValueString {
+			return Value{}, fmt.Errorf("property is a number, expected a string")
+		}
+		return NumberValue(t, v), nil
+	case string:
+		return parseValue(t, v)
+	default:
+		return Value{}, fmt.Errorf("property has unsupported type %T", raw)
+	}
+}
@@ -0,0 +1,144 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestDriftChecker(t *testing.T) {
+	const asgURN = "urn:pulumi:dev::app::aws:autoscaling/group:Group::workers"
+
+	tests := []struct {
+		name        string
+		drift       DriftConfig
+		live        any
+		wantDrifted bool
+		wantAction  string
+		wantConfig  string
+		wantUps     int
+		wantError   bool
+	}{
+		{name: "in sync", drift: DriftConfig{Property: "desiredCapacity"}, live: 3.0, wantConfig: "3"},
+		{name: "report", drift: DriftConfig{Property: "desiredCapacity"}, live: 5.0, wantDrifted: true, wantConfig: "3"},
+		{
+			name:        "adopt",
+			drift:       DriftConfig{Property: "desiredCapacity", Policy: DriftAdopt},
+			live:        5.0,
+			wantDrifted: true, wantAction: "adopted", wantConfig: "5",
+		},
+		{
+			name:        "correct",
+			drift:       DriftConfig{Property: "desiredCapacity", Policy: DriftCorrect},
+			live:        5.0,
+			wantDrifted: true, wantAction: "corrected", wantConfig: "3", wantUps: 1,
+		},
+		{name: "missing property", drift: DriftConfig{Property: "size"}, live: 3.0, wantConfig: "3", wantError: true},
+		{
+			name:      "secret property",
+			drift:     DriftConfig{Property: "desiredCapacity"},
+			live:      map[string]any{secretSig: "1b47061264138c4ac30d75fd1eb44270", "ciphertext": "x"},
+			wantError: true, wantConfig: "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeStack(map[string]string{"workerCount": "3"})
+			fs.resources = []apitype.ResourceV3{{
+				URN:     resource.URN(asgURN),
+				Outputs: map[string]any{"desiredCapacity": tt.live},
+			}}
+			drift := tt.drift
+			rules := map[string]ScalingRule{"workers": {
+				PoolName: "workers", TargetURN: asgURN, ConfigKey: "workerCount", Max: 10, Drift: &drift,
+			}}
+			engine := NewEngine(rules, newFakeStateManager(fs))
+			checker := NewDriftChecker(engine, 0)
+
+			statuses := checker.Check(context.Background())
+			if len(statuses) != 1 {
+				t.Fatalf("got %d statuses", len(statuses))
+			}
+			st := statuses[0]
+			if st.Drifted != tt.wantDrifted || st.Action != tt.wantAction || (st.Error != "") != tt.wantError {
+				t.Errorf("status = %+v", st)
+			}
+			if fs.config["workerCount"].Value != tt.wantConfig {
+				t.Errorf("workerCount = %s, want %s", fs.config["workerCount"].Value, tt.wantConfig)
+			}
+			if fs.upCalls != tt.wantUps {
+				t.Errorf("upCalls = %d, want %d", fs.upCalls, tt.wantUps)
+			}
+			if engine.Drifted["workers"] != (tt.wantDrifted && tt.wantAction == "") {
+				t.Errorf("engine Drifted = %v", engine.Drifted["workers"])
+			}
+			if len(checker.Statuses()) != 1 {
+				t.Errorf("Statuses() = %v", checker.Statuses())
+			}
+		})
+	}
+
+	t.Run("refresh runs without the engine lock", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"workerCount": "3"})
+		fs.resources = []apitype.ResourceV3{{URN: resource.URN(asgURN), Outputs: map[string]any{"desiredCapacity": 5.0}}}
+		rules := map[string]ScalingRule{"workers": {
+			PoolName: "workers", TargetURN: asgURN, ConfigKey: "workerCount", Max: 10,
+			Drift: &DriftConfig{Property: "desiredCapacity", Policy: DriftAdopt, Refresh: true},
+		}}
+		engine := NewEngine(rules, newFakeStateManager(fs))
+		fs.onRefresh = func() {
+			if !engine.mu.TryLock() {
+				t.Error("engine lock held during refresh")
+				return
+			}
+			// A scaling job runs while the live value is read.
+			fs.config["workerCount"] = auto.ConfigValue{Value: "5"}
+			engine.mu.Unlock()
+		}
+
+		st := NewDriftChecker(engine, 0).Check(context.Background())[0]
+		if st.Drifted || st.Action != "" || st.Error != "" {
+			t.Errorf("status = %+v, want the check skipped after a concurrent scale", st)
+		}
+		if fs.config["workerCount"].Value != "5" {
+			t.Errorf("workerCount = %s, want the scaled value 5", fs.config["workerCount"].Value)
+		}
+	})
+
+	t.Run("adopt keeps secret keys secret", func(t *testing.T) {
+		fs := newFakeStack(nil)
+		fs.config["workerCount"] = auto.ConfigValue{Value: "3", Secret: true}
+		fs.config["maxSize"] = auto.ConfigValue{Value: "5", Secret: true}
+		fs.resources = []apitype.ResourceV3{{URN: resource.URN(asgURN), Outputs: map[string]any{"desiredCapacity": 6.0}}}
+		rules := map[string]ScalingRule{"workers": {
+			PoolName: "workers", TargetURN: asgURN, ConfigKey: "workerCount", Max: 10,
+			DerivedKeys: []DerivedKey{{ConfigKey: "maxSize", Value: "desired + 2"}},
+			Drift:       &DriftConfig{Property: "desiredCapacity", Policy: DriftAdopt},
+		}}
+		st := NewDriftChecker(NewEngine(rules, newFakeStateManager(fs)), 0).Check(context.Background())[0]
+		if st.Action != "adopted" {
+			t.Fatalf("status = %+v, want adopted", st)
+		}
+		for k, want := range map[string]string{"workerCount": "6", "maxSize": "8"} {
+			if got := fs.config[k]; got.Value != want || !got.Secret {
+				t.Errorf("%s = %+v, want secret %s", k, got, want)
+			}
+		}
+	})
+
+	t.Run("refresh targets the pool", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"workerCount": "3"})
+		fs.resources = []apitype.ResourceV3{{URN: resource.URN(asgURN), Outputs: map[string]any{"scaling": map[string]any{"desired": "3"}}}}
+		rule := ScalingRule{PoolName: "workers", TargetURN: asgURN, ConfigKey: "workerCount"}
+		live, err := newFakeStateManager(fs).LiveValue(context.Background(), rule, "scaling.desired", true)
+		if err != nil || live.Number != 3 {
+			t.Fatalf("LiveValue() = %v, %v", live, err)
+		}
+		if fs.refreshCalls != 1 || len(fs.refreshOpts.Target) != 1 || fs.refreshOpts.Target[0] != asgURN {
+			t.Errorf("refresh calls = %d, opts = %+v", fs.refreshCalls, fs.refreshOpts)
+		}
+	})
+}
@@ -2,33 +2,101 @@ package autoscaler
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/rshade/pulumi-scale/internal/webhooks"
 )
 
 // Engine is responsible for processing ScalingIntents and triggering state updates.
 type Engine struct {
-	Rules       map[string]ScalingRule
-	State       *StateManager // To be implemented in US3
-	LastScaled  map[string]time.Time
-	mu          sync.Mutex
-	IntentChan  chan webhooks.ScalingIntent
+	// Rules is swapped by SetRules while the engine runs; use RulesSnapshot to read it.
+	Rules      map[string]ScalingRule
+	rulesMu    sync.RWMutex
+	State      *StateManager // To be implemented in US3
+	LastScaled map[string]time.Time
+	// Drifted marks pools whose config no longer matches the deployed infrastructure
+	// because an up failed and the config was not rolled back.
+	Drifted    map[string]bool
+	mu         sync.Mutex
+	IntentChan chan webhooks.ScalingIntent
+
+	// PinnedAfter is how long a pool may stay pinned at its min or max before a job is marked
+	// with PinnedAlert, for rules without pinnedAlert. 0 only alerts for rules that set it.
+	PinnedAfter time.Duration
+
+	observersMu sync.RWMutex
+	observers   []JobObserver
+
+	// Runtime state for Status, guarded by statusMu rather than mu.
+	statusMu sync.Mutex
+	pools    map[string]*poolState
+	inFlight *InFlightJob
+
+	running atomic.Bool
 }
 
+// JobObserver is called with every intent the engine finishes processing, whatever its outcome.
+// rule is the zero ScalingRule when the pool has no rule. Observers run on the engine's
+// goroutine and must not block.
+type JobObserver func(intent webhooks.ScalingIntent, rule ScalingRule, result JobResult)
+
 func NewEngine(rules map[string]ScalingRule, state *StateManager) *Engine {
 	return &Engine{
 		Rules:      rules,
 		State:      state,
 		LastScaled: make(map[string]time.Time),
+		Drifted:    make(map[string]bool),
 		IntentChan: make(chan webhooks.ScalingIntent, 100),
 	}
 }
 
+// SetRules atomically replaces the rule set. Intents already queued are
+// evaluated against the new rules; an intent being applied keeps the rule it started with.
+func (e *Engine) SetRules(rules map[string]ScalingRule) {
+	e.rulesMu.Lock()
+	defer e.rulesMu.Unlock()
+	e.Rules = rules
+}
+
+// RulesSnapshot returns a copy of the current rule set.
+func (e *Engine) RulesSnapshot() map[string]ScalingRule {
+	e.rulesMu.RLock()
+	defer e.rulesMu.RUnlock()
+	return maps.Clone(e.Rules)
+}
+
+func (e *Engine) rule(pool string) (ScalingRule, bool) {
+	e.rulesMu.RLock()
+	defer e.rulesMu.RUnlock()
+	rule, ok := e.Rules[pool]
+	return rule, ok
+}
+
+// Observe registers fn to be called with the result of every processed intent.
+func (e *Engine) Observe(fn JobObserver) {
+	e.observersMu.Lock()
+	defer e.observersMu.Unlock()
+	e.observers = append(e.observers, fn)
+}
+
+// Running reports whether the intent loop started by Start is active.
+func (e *Engine) Running() bool {
+	return e.running.Load()
+}
+
 func (e *Engine) Start(ctx context.Context) {
+	e.running.Store(true)
+	defer e.running.Store(false)
 	log.Info().Msg("Engine started, waiting for intents...")
 	for {
 		select {
@@ -40,103 +108,240 @@ func (e *Engine) Start(ctx context.Context) {
 	}
 }
 
-func (e *Engine) ProcessIntent(ctx context.Context, intent webhooks.ScalingIntent) {
+// ProcessIntent evaluates a single intent against its rule and applies the result.
+// The job logs through the logger attached to ctx, or the global one, with its job ID,
+// pool, request ID and stack added.
+func (e *Engine) ProcessIntent(ctx context.Context, intent webhooks.ScalingIntent) JobResult {
+	ctx, span := tracer.Start(intentContext(ctx, intent), "autoscaler.process_intent", trace.WithAttributes(
+		attrPool.String(intent.TargetPool),
+		attrAction.String(string(intent.Action)),
+		attrSource.String(intent.Source),
+		attrDryRun.Bool(intent.DryRun),
+	))
+	jobID := newJobID()
+	fields := logFrom(ctx).With().Str("jobId", jobID).Str("pool", intent.TargetPool)
+	if intent.RequestID != "" {
+		fields = fields.Str("requestId", intent.RequestID)
+	}
+	if e.State != nil {
+		span.SetAttributes(attrStack.String(e.State.StackName))
+		fields = fields.Str("stack", e.State.StackName)
+	}
+	ctx = fields.Logger().WithContext(ctx)
+
 	e.mu.Lock()
-	defer e.mu.Unlock()
+	done := e.startJob(jobID, intent, time.Now())
+	rule, result := e.processIntent(ctx, intent)
+	result.JobID = jobID
+	done(result)
+	e.trackPinned(ctx, rule, &result)
+	e.mu.Unlock()
+
+	span.SetAttributes(
+		attrStatus.String(string(result.Status)),
+		attrSkipped.String(string(result.Skipped)),
+		attrDecision.String(string(result.Decision)),
+	)
+	if result.Status == JobFailed {
+		span.SetStatus(codes.Error, result.Error)
+	}
+	span.End()
+
+	e.observersMu.RLock()
+	observers := e.observers
+	e.observersMu.RUnlock()
+	for _, fn := range observers {
+		fn(intent, rule, result)
+	}
+	return result
+}
 
-	log.Info().
-		Str("pool", intent.TargetPool).
+// processIntent does the work of ProcessIntent. The caller holds e.mu.
+func (e *Engine) processIntent(ctx context.Context, intent webhooks.ScalingIntent) (ScalingRule, JobResult) {
+	ctx = withSource(ctx, intent.Source)
+	logger := logFrom(ctx)
+
+	var rule ScalingRule
+	result := JobResult{
+		Pool:      intent.TargetPool,
+		Status:    JobSkipped,
+		StartedAt: time.Now(),
+	}
+	finish := func() (ScalingRule, JobResult) {
+		result.FinishedAt = time.Now()
+		return rule, result
+	}
+
+	rule, ok := e.rule(intent.TargetPool)
+	event := logger.Info().
 		Str("action", string(intent.Action)).
-		Int("value", intent.Value).
-		Str("reason", intent.Reason).
-		Msg("Processing intent")
+		Str("reason", intent.Reason)
+	if ok && rule.Secret {
+		event = event.Str("value", redacted)
+	} else {
+		event = event.Float64("value", intent.Value).Str("text", intent.Text)
+	}
+	event.Msg("Processing intent")
 
-	rule, ok := e.Rules[intent.TargetPool]
 	if !ok {
-		log.Error().Str("pool", intent.TargetPool).Msg("No rule found for pool")
-		return
+		logger.Error().Msg("No rule found for pool")
+		result.Skipped = SkipNoRule
+		result.Error = "no rule found for pool"
+		return finish()
+	}
+
+	if e.Paused(rule.PoolName) {
+		logger.Info().Msg("Skipping intent: Pool is paused")
+		result.Skipped = SkipPaused
+		return finish()
 	}
 
 	// Cooldown Check (T018)
-	if !e.checkCooldown(rule) {
-		log.Info().Str("pool", intent.TargetPool).Msg("Skipping intent: Cooldown active")
-		return
+	if until, ok := e.cooldownUntil(rule); !ok {
+		logger.Info().Time("until", until).Msg("Skipping intent: Cooldown active")
+		result.Skipped = SkipCooldown
+		result.CooldownUntil = until
+		return finish()
 	}
 
-	// Retrieve Current Count
-	current, err := e.State.GetCurrentCount(ctx, rule.ConfigKey)
+	// Retrieve Current Value
+	current, err := e.State.GetCurrentValue(ctx, rule)
+	currentKnown := err == nil
 	if err != nil {
 		// Log warning, but maybe proceed if ActionSet?
 		// If ActionDelta, we MUST have current.
 		if intent.Action == webhooks.ActionDelta {
-			log.Error().Err(err).Msg("Error getting current count for delta scaling")
-			return
+			logger.Error().Err(err).Msg("Error getting current value for delta scaling")
+			result.Status = JobFailed
+			result.Error = err.Error()
+			return finish()
 		}
 		// If ActionSet, we might not strictly need current, but good for logging.
-		log.Warn().Err(err).Msg("Could not get current count. Assuming unknown.")
-	}
-
-	var target int
-	if intent.Action == webhooks.ActionSet {
-		target = intent.Value
-	} else {
-		target = current + intent.Value
+		logger.Warn().Err(err).Msg("Could not get current value. Assuming unknown.")
 	}
 
-	// Guardrails
-	if target < rule.Min {
-		target = rule.Min
+	target, err := calculateTarget(rule, intent, current)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid intent for pool")
+		result.Status = JobFailed
+		result.Error = err.Error()
+		return finish()
 	}
-	if target > rule.Max {
-		target = rule.Max
+	target.Secret = target.Secret || rule.Secret
+	result.Previous = current
+	result.Target = target
+	if rule.valueType() != ValueString {
+		if requested := requestedTarget(intent, current); requested < rule.Min {
+			result.Clamped, result.Requested = ClampMin, requested
+		} else if requested > rule.Max {
+			result.Clamped, result.Requested = ClampMax, requested
+		}
+		// The unclamped target of a secret pool is as sensitive as the value itself.
+		if rule.Secret {
+			result.Requested = 0
+		}
 	}
 
-	log.Info().
-		Str("pool", intent.TargetPool).
-		Int("target", target).
-		Int("current", current).
+	logger.Info().
+		Stringer("target", target).
+		Stringer("current", current).
 		Msg("Calculated target")
 
-	if target == current {
-		log.Info().Msg("Target equals current. No change needed.")
-		return
+	if currentKnown && target.Equal(current) {
+		logger.Info().Msg("Target equals current. No change needed.")
+		result.Skipped = SkipUnchanged
+		return finish()
 	}
 
 	// Apply State
 	if intent.DryRun {
-		log.Info().Int("target", target).Msg("DryRun detected. Previewing scale...")
-		diff, err := e.State.Preview(ctx, rule, target)
+		logger.Info().Stringer("target", target).Msg("DryRun detected. Previewing scale...")
+		preview, err := e.State.Preview(ctx, rule, target)
 		if err != nil {
-			log.Error().Err(err).Msg("Error previewing scaling")
-			return
+			logger.Error().Err(err).Msg("Error previewing scaling")
+			result.Status = JobFailed
+			result.Error = err.Error()
+			return finish()
+		}
+		if len(preview.Extra) > 0 {
+			logger.Info().
+				Strs("extraResources", preview.Extra).
+				Msg("DryRun will also update resources outside the declared targets")
 		}
-		log.Info().Msgf("DryRun Result:\n%s", diff)
+		logger.Info().Msgf("DryRun Result:\n%s", preview.StdOut)
 		// Do not update LastScaled or persist
-		return
+		result.Status = JobPreviewed
+		return finish()
 	}
 
-	startTime := time.Now()
-	if err := e.State.Apply(ctx, rule, target); err != nil {
-		log.Error().Err(err).Msg("Error applying scaling")
-		return
+	applied, err := e.State.Apply(ctx, rule, target)
+	applied.StartedAt = result.StartedAt
+	applied.Clamped, applied.Requested = result.Clamped, result.Requested
+	if err != nil {
+		if applied.Decision == DecisionDrifted || applied.Decision == DecisionRollbackFailed {
+			e.setDrifted(rule.PoolName, true)
+		}
+		logger.Error().
+			Err(err).
+			Str("decision", string(applied.Decision)).
+			Int("attempts", applied.Attempts).
+			Msg("Error applying scaling")
+		return rule, applied
 	}
-	duration := time.Since(startTime)
-	log.Info().
-		Str("pool", intent.TargetPool).
-		Int("target", target).
+	duration := applied.FinishedAt.Sub(applied.StartedAt)
+	logger.Info().
+		Stringer("target", target).
 		Dur("duration", duration).
+		Str("decision", string(applied.Decision)).
 		Msg("Successfully scaled")
 
 	e.LastScaled[rule.PoolName] = time.Now()
+	e.setDrifted(rule.PoolName, false)
+	return rule, applied
+}
+
+// calculateTarget applies the intent to the current value and clamps the result to the rule's guardrails.
+func calculateTarget(rule ScalingRule, intent webhooks.ScalingIntent, current Value) (Value, error) {
+	text := intent.Text
+	if rule.Secret {
+		text = redacted
+	}
+	if rule.valueType() == ValueString {
+		if intent.Action != webhooks.ActionSet {
+			return Value{}, fmt.Errorf("pool %s holds a string value and only supports set", rule.PoolName)
+		}
+		if intent.Text == "" {
+			return Value{}, fmt.Errorf("pool %s holds a string value; send a string, not a number", rule.PoolName)
+		}
+		if len(rule.Allowed) > 0 && !slices.Contains(rule.Allowed, intent.Text) {
+			return Value{}, fmt.Errorf("value %q is not allowed for pool %s", text, rule.PoolName)
+		}
+		return StringValue(intent.Text), nil
+	}
+
+	if intent.Text != "" {
+		return Value{}, fmt.Errorf("pool %s holds numeric (%s) values; send a number, not %q", rule.PoolName, rule.valueType(), text)
+	}
+
+	// Guardrails
+	target := math.Max(rule.Min, math.Min(rule.Max, requestedTarget(intent, current)))
+	return NumberValue(rule.valueType(), target), nil
 }
 
-func (e *Engine) checkCooldown(rule ScalingRule) bool {
+// requestedTarget is the numeric target an intent asks for, before guardrails.
+func requestedTarget(intent webhooks.ScalingIntent, current Value) float64 {
+	if intent.Action == webhooks.ActionSet {
+		return intent.Value
+	}
+	return current.Number + intent.Value
+}
+
+// cooldownUntil returns when the pool's cooldown ends and whether it already has.
+func (e *Engine) cooldownUntil(rule ScalingRule) (time.Time, bool) {
 	last, ok := e.LastScaled[rule.PoolName]
 	if !ok {
-		return true // Never scaled
-	}
-	if time.Since(last) < time.Duration(rule.CooldownSeconds)*time.Second {
-		return false
+		return time.Time{}, true // Never scaled
 	}
-	return true
+	until := last.Add(time.Duration(rule.CooldownSeconds) * time.Second)
+	return until, !time.Now().Before(until)
 }
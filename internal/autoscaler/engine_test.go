@@ -0,0 +1,239 @@
+package autoscaler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestCalculateTarget(t *testing.T) {
+	intRule := ScalingRule{PoolName: "workers", Min: 1, Max: 10}
+	floatRule := ScalingRule{PoolName: "gpu", Type: ValueFloat, Min: 0.25, Max: 2}
+	stringRule := ScalingRule{PoolName: "type", Type: ValueString, Allowed: []string{"m5.large", "m5.xlarge"}}
+
+	tests := []struct {
+		name    string
+		rule    ScalingRule
+		intent  webhooks.ScalingIntent
+		current Value
+		want    Value
+		wantErr bool
+	}{
+		{
+			name:    "int delta",
+			rule:    intRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionDelta, Value: 2},
+			current: IntValue(3),
+			want:    IntValue(5),
+		},
+		{
+			name:    "int set clamped to max",
+			rule:    intRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionSet, Value: 50},
+			current: IntValue(3),
+			want:    IntValue(10),
+		},
+		{
+			name:    "int delta clamped to min",
+			rule:    intRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionDelta, Value: -5},
+			current: IntValue(3),
+			want:    IntValue(1),
+		},
+		{
+			name:    "float delta",
+			rule:    floatRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionDelta, Value: 0.25},
+			current: FloatValue(0.5),
+			want:    FloatValue(0.75),
+		},
+		{
+			name:    "string set",
+			rule:    stringRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionSet, Text: "m5.xlarge"},
+			current: StringValue("m5.large"),
+			want:    StringValue("m5.xlarge"),
+		},
+		{
+			name:    "string not allowed",
+			rule:    stringRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionSet, Text: "p4d.24xlarge"},
+			wantErr: true,
+		},
+		{
+			name:    "string sent to int pool",
+			rule:    intRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionSet, Text: "10"},
+			current: IntValue(3),
+			wantErr: true,
+		},
+		{
+			name:    "number sent to string pool",
+			rule:    ScalingRule{PoolName: "type", Type: ValueString},
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionSet, Value: 10},
+			wantErr: true,
+		},
+		{
+			name:    "string delta rejected",
+			rule:    stringRule,
+			intent:  webhooks.ScalingIntent{Action: webhooks.ActionDelta, Value: 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculateTarget(tt.rule, tt.intent, tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("calculateTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("calculateTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessIntentObservers(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	fs.upErrs = []error{conflictError()}
+	sm := newFakeStateManager(fs)
+	sm.Clock = &fakeClock{}
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 5, CooldownSeconds: 60}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, sm)
+
+	var observed []JobResult
+	e.Observe(func(intent webhooks.ScalingIntent, got ScalingRule, result JobResult) {
+		if got.PoolName != intent.TargetPool && result.Skipped != SkipNoRule {
+			t.Errorf("observer got rule %q for pool %q", got.PoolName, intent.TargetPool)
+		}
+		observed = append(observed, result)
+	})
+
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 9})
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 2})
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "other", Action: webhooks.ActionSet, Value: 2})
+
+	if len(observed) != 3 {
+		t.Fatalf("observed %d results, want 3", len(observed))
+	}
+	applied := observed[0]
+	if applied.Status != JobSucceeded || !applied.Target.Equal(IntValue(5)) {
+		t.Errorf("applied = %+v, want succeeded at 5", applied)
+	}
+	if applied.Clamped != ClampMax || applied.Requested != 9 {
+		t.Errorf("Clamped = %q, Requested = %v; want max, 9", applied.Clamped, applied.Requested)
+	}
+	if applied.ConflictRetries != 1 || applied.Attempts != 1 {
+		t.Errorf("ConflictRetries = %d, Attempts = %d; want 1, 1", applied.ConflictRetries, applied.Attempts)
+	}
+	if applied.UpdateVersion != 2 {
+		t.Errorf("UpdateVersion = %d, want the version of the successful up (2)", applied.UpdateVersion)
+	}
+	if observed[1].Skipped != SkipCooldown || observed[1].CooldownUntil.IsZero() {
+		t.Errorf("second intent Skipped = %q, CooldownUntil = %v; want cooldown with its end", observed[1].Skipped, observed[1].CooldownUntil)
+	}
+	if observed[2].Skipped != SkipNoRule {
+		t.Errorf("unknown pool Skipped = %q, want no_rule", observed[2].Skipped)
+	}
+}
+
+func TestProcessIntentValueMismatch(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3", "instanceType": "m5.large"})
+	rules := map[string]ScalingRule{
+		"workers": {PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10},
+		"type":    {PoolName: "type", TargetURN: "urn:b", ConfigKey: "instanceType", Type: ValueString},
+	}
+	e := NewEngine(rules, newFakeStateManager(fs))
+
+	// {"value":"10"} on a numeric pool must not clamp a zero Value to min and apply it.
+	result := e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Text: "10"})
+	if result.Status != JobFailed {
+		t.Errorf("string on numeric pool = %+v, want failed", result)
+	}
+	// {"value":10} on a string pool without an allowed list must not write "".
+	result = e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "type", Action: webhooks.ActionSet, Value: 10})
+	if result.Status != JobFailed {
+		t.Errorf("number on string pool = %+v, want failed", result)
+	}
+
+	if fs.upCalls != 0 || fs.config["workerCount"].Value != "3" || fs.config["instanceType"].Value != "m5.large" {
+		t.Errorf("config = %v after %d ups, want it untouched", fs.config, fs.upCalls)
+	}
+}
+
+func TestProcessIntentLogFields(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := zerolog.New(&buf).WithContext(context.Background())
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	fs.upErrs = []error{conflictError()}
+	sm := newFakeStateManager(fs)
+	sm.Clock = &fakeClock{}
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, sm)
+
+	result := e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 5, RequestID: "host/abc-000001"})
+	if result.Status != JobSucceeded {
+		t.Fatalf("result = %+v, want succeeded", result)
+	}
+
+	messages := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if entry["jobId"] != result.JobID || entry["pool"] != "workers" || entry["requestId"] != "host/abc-000001" {
+			t.Errorf("log line %s lacks the job's jobId, pool or requestId", line)
+		}
+		messages[entry["message"].(string)] = true
+	}
+	// One line from the engine and one from the state manager's conflict retry.
+	if !messages["Calculated target"] || !messages["Concurrent update detected. Retrying..."] {
+		t.Errorf("messages = %v, want engine and state manager lines", messages)
+	}
+}
+
+func TestProcessIntentRedactsSecretPools(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := zerolog.New(&buf).WithContext(context.Background())
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 8000, Secret: true}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, newFakeStateManager(fs))
+	var observed []JobResult
+	e.Observe(func(_ webhooks.ScalingIntent, _ ScalingRule, result JobResult) {
+		observed = append(observed, result)
+	})
+
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 4242})
+	// Clamped to max: the unclamped request is as secret as the value.
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 98765})
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Text: "s3cret-size"})
+
+	results, err := json.Marshal(observed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"4242", "98765", "s3cret-size"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("log contains the secret value %s:\n%s", secret, buf.String())
+		}
+		if strings.Contains(string(results), secret) {
+			t.Errorf("job results contain the secret value %s: %s", secret, results)
+		}
+	}
+	if !strings.Contains(buf.String(), "Processing intent") {
+		t.Error("no Processing intent line was logged")
+	}
+	if len(observed) != 3 || observed[1].Clamped != ClampMax {
+		t.Errorf("observed = %+v, want the second job clamped to max", observed)
+	}
+}
@@ -0,0 +1,77 @@
+package autoscaler
+
+import (
+	"errors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// ErrorKind classifies failures returned by the Automation API.
+type ErrorKind string
+
+const (
+	ErrorConcurrentUpdate ErrorKind = "concurrent_update" // stack locked by another update
+	ErrorCompilation      ErrorKind = "compilation"       // program failed to build
+	ErrorRuntime          ErrorKind = "runtime"           // program failed while running
+	ErrorEngine           ErrorKind = "engine"            // pulumi engine bug
+	ErrorUnknown          ErrorKind = "unknown"           // anything else, e.g. provider errors
+)
+
+// Retryable reports whether an operation failing with this kind may succeed if repeated unchanged.
+func (k ErrorKind) Retryable() bool {
+	return k == ErrorConcurrentUpdate
+}
+
+// Permanent reports whether an operation failing with this kind fails the same way every
+// time, so retrying it after a backoff is pointless.
+func (k ErrorKind) Permanent() bool {
+	return k == ErrorCompilation || k == ErrorRuntime || k == ErrorEngine
+}
+
+// StackError is an Automation API error annotated with its ErrorKind.
+type StackError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *StackError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StackError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError maps an error onto the ErrorKind taxonomy.
+// Errors already wrapped in a StackError keep their kind; anything else is
+// inspected with the Automation API's error helpers.
+func classifyError(err error) ErrorKind {
+	var se *StackError
+	if errors.As(err, &se) {
+		return se.Kind
+	}
+	switch {
+	case auto.IsConcurrentUpdateError(err):
+		return ErrorConcurrentUpdate
+	case auto.IsCompilationError(err):
+		return ErrorCompilation
+	case auto.IsRuntimeError(err):
+		return ErrorRuntime
+	case auto.IsUnexpectedEngineError(err):
+		return ErrorEngine
+	default:
+		return ErrorUnknown
+	}
+}
+
+// wrapStackError wraps err in a StackError, classifying it if needed.
+func wrapStackError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *StackError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &StackError{Kind: classifyError(err), Err: err}
+}
@@ -0,0 +1,267 @@
+package autoscaler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+// expr is a parsed arithmetic expression used to derive config values from the desired size,
+// e.g. "desired + 2" or "max(desired * 2, 10)".
+//
+// Supported syntax: numbers, the variables passed to eval, + - * /, unary minus,
+// parentheses and the functions min, max, ceil, floor and round.
+type expr interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type numberExpr float64
+
+type varExpr string
+
+type unaryExpr struct {
+	x expr
+}
+
+type binaryExpr struct {
+	op   byte
+	l, r expr
+}
+
+type callExpr struct {
+	fn   string
+	args []expr
+}
+
+func (n numberExpr) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (v varExpr) eval(vars map[string]float64) (float64, error) {
+	val, ok := vars[string(v)]
+	if !ok {
+		return 0, fmt.Errorf("unknown variable %q", string(v))
+	}
+	return val, nil
+}
+
+func (u unaryExpr) eval(vars map[string]float64) (float64, error) {
+	x, err := u.x.eval(vars)
+	return -x, err
+}
+
+func (b binaryExpr) eval(vars map[string]float64) (float64, error) {
+	l, err := b.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := b.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	}
+}
+
+func (c callExpr) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(c.args))
+	for i, a := range c.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch c.fn {
+	case "min":
+		return minMax(args, math.Min)
+	case "max":
+		return minMax(args, math.Max)
+	case "ceil", "floor", "round":
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%s takes exactly one argument", c.fn)
+		}
+		switch c.fn {
+		case "ceil":
+			return math.Ceil(args[0]), nil
+		case "floor":
+			return math.Floor(args[0]), nil
+		default:
+			return math.Round(args[0]), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown function %q", c.fn)
+}
+
+func minMax(args []float64, pick func(a, b float64) float64) (float64, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("min/max need at least one argument")
+	}
+	out := args[0]
+	for _, a := range args[1:] {
+		out = pick(out, a)
+	}
+	return out, nil
+}
+
+// parseExpr parses an expression. Variables are resolved at evaluation time.
+func parseExpr(src string) (expr, error) {
+	p := &exprParser{src: src}
+	p.next()
+	e, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("unexpected %q at offset %d in %q", p.tok, p.start, src)
+	}
+	return e, nil
+}
+
+// exprParser is a recursive descent parser over a single-token lookahead.
+type exprParser struct {
+	src   string
+	pos   int
+	start int
+	tok   string // current token; "" at end of input
+}
+
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case unicode.IsLetter(rune(c)) || c == '_':
+		for p.pos < len(p.src) && (unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '_') {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[p.start:p.pos]
+}
+
+func (p *exprParser) expect(tok string) error {
+	if p.tok != tok {
+		return fmt.Errorf("expected %q at offset %d in %q", tok, p.start, p.src)
+	}
+	p.next()
+	return nil
+}
+
+// sum := product (("+" | "-") product)*
+func (p *exprParser) parseSum() (expr, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok[0]
+		p.next()
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryExpr{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+// product := unary (("*" | "/") unary)*
+func (p *exprParser) parseProduct() (expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "*" || p.tok == "/" {
+		op := p.tok[0]
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryExpr{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+// unary := "-" unary | primary
+func (p *exprParser) parseUnary() (expr, error) {
+	if p.tok == "-" {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+// primary := number | ident | ident "(" args ")" | "(" sum ")"
+func (p *exprParser) parsePrimary() (expr, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression %q", p.src)
+	case tok == "(":
+		p.next()
+		e, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in %q", tok, p.src)
+		}
+		p.next()
+		return numberExpr(f), nil
+	case unicode.IsLetter(rune(tok[0])) || tok[0] == '_':
+		p.next()
+		if p.tok != "(" {
+			return varExpr(tok), nil
+		}
+		p.next()
+		var args []expr
+		for p.tok != ")" {
+			a, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if p.tok != "," {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return callExpr{fn: tok, args: args}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d in %q", tok, p.start, p.src)
+}
@@ -0,0 +1,45 @@
+package autoscaler
+
+import "testing"
+
+func TestExpr(t *testing.T) {
+	vars := map[string]float64{"desired": 4, "current": 3, "min": 1, "max": 10}
+
+	tests := []struct {
+		src     string
+		want    float64
+		wantErr bool
+	}{
+		{src: "desired", want: 4},
+		{src: "desired + 2", want: 6},
+		{src: "desired * 2 - 1", want: 7},
+		{src: "(desired + 2) * 2", want: 12},
+		{src: "-desired + 10", want: 6},
+		{src: "desired / 8", want: 0.5},
+		{src: "ceil(desired / 3)", want: 2},
+		{src: "max(desired * 3, max)", want: 12},
+		{src: "min(desired, current, 2.5)", want: 2.5},
+		{src: "desired + unknown", wantErr: true},
+		{src: "desired / 0", wantErr: true},
+		{src: "desired +", wantErr: true},
+		{src: "(desired", wantErr: true},
+		{src: "desired 2", wantErr: true},
+		{src: "sqrt(desired)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := parseExpr(tt.src)
+			var got float64
+			if err == nil {
+				got, err = e.eval(vars)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/rs/zerolog/log"
+)
+
+// GitSource locates a Pulumi program in a git repository, so no local checkout is needed.
+type GitSource struct {
+	URL string
+
+	// (Optional) Branch or tag to follow. Defaults to the remote's default branch.
+	Branch string
+
+	// (Optional) Commit to pin. Pinned sources are cloned once and never pulled.
+	Commit string
+
+	// (Optional) Directory of the Pulumi program relative to the repository root.
+	ProjectPath string
+
+	// (Optional) Credentials for private repositories.
+	Auth *auto.GitAuth
+
+	// How often to fetch Branch for new commits. Zero clones once.
+	PullInterval time.Duration
+}
+
+// NewGitStateManager manages a stack whose program is cloned from a git repository.
+// Every update runs against a single commit, which is recorded in its JobResult.
+func NewGitStateManager(stackName string, src GitSource) *StateManager {
+	co := &gitCheckout{src: src, stackName: stackName, written: make(map[string]configWrite)}
+	return &StateManager{
+		StackName: stackName,
+		open:      co.open,
+	}
+}
+
+// gitStack is a stack in a clone of a specific commit.
+type gitStack struct {
+	stack
+	commit   string
+	checkout *gitCheckout
+}
+
+// Commit returns the SHA the stack's program was checked out at.
+func (g *gitStack) Commit() string { return g.commit }
+
+// SetConfigWithOptions records the write so it survives the next pull: stack config lives in
+// Pulumi.<stack>.yaml inside the clone, which a fresh clone would otherwise reset.
+// Writes to another config file, like a preview's staged copy, aren't recorded.
+func (g *gitStack) SetConfigWithOptions(ctx context.Context, key string, val auto.ConfigValue, opts *auto.ConfigOptions) error {
+	if err := g.stack.SetConfigWithOptions(ctx, key, val, opts); err != nil {
+		return err
+	}
+	if opts != nil && opts.ConfigFile != "" {
+		return nil
+	}
+	g.checkout.remember(key, val, opts)
+	return nil
+}
+
+// RemoveConfigWithOptions forgets an earlier write so the next pull doesn't bring the key back.
+func (g *gitStack) RemoveConfigWithOptions(ctx context.Context, key string, opts *auto.ConfigOptions) error {
+	if err := g.stack.RemoveConfigWithOptions(ctx, key, opts); err != nil {
+		return err
+	}
+	g.checkout.forget(key)
+	return nil
+}
+
+// gitCheckout keeps the current clone of a GitSource and replaces it when the branch moves.
+type gitCheckout struct {
+	src       GitSource
+	stackName string
+
+	mu       sync.Mutex
+	current  *gitStack
+	dir      string
+	prevDir  string
+	pulledAt time.Time
+	written  map[string]configWrite
+
+	// cloneFn replaces clone in tests.
+	cloneFn func(ctx context.Context) (*gitStack, string, error)
+}
+
+func (g *gitCheckout) remember(key string, val auto.ConfigValue, opts *auto.ConfigOptions) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.written[key] = configWrite{key: key, value: val, opts: opts}
+}
+
+func (g *gitCheckout) forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.written, key)
+}
+
+// open returns the current clone, pulling first if PullInterval has elapsed. Callers keep the
+// returned stack for the whole operation, so an update is never split across commits.
+func (g *gitCheckout) open(ctx context.Context) (stack, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fresh := g.current != nil &&
+		(g.src.Commit != "" || g.src.PullInterval <= 0 || time.Since(g.pulledAt) < g.src.PullInterval)
+	if fresh {
+		return g.current, nil
+	}
+
+	clone := g.clone
+	if g.cloneFn != nil {
+		clone = g.cloneFn
+	}
+	st, dir, err := clone(ctx)
+	if err != nil {
+		if g.current != nil {
+			log.Warn().Err(err).Str("url", g.src.URL).Str("commit", g.current.commit).Msg("Git pull failed; keeping current commit")
+			g.pulledAt = time.Now()
+			return g.current, nil
+		}
+		return nil, err
+	}
+	g.pulledAt = time.Now()
+	if g.current != nil && st.commit == g.current.commit {
+		os.RemoveAll(dir)
+		return g.current, nil
+	}
+	if err := g.replay(ctx, st); err != nil {
+		os.RemoveAll(dir)
+		if g.current != nil {
+			log.Warn().Err(err).Str("url", g.src.URL).Str("commit", g.current.commit).Msg("Git pull failed; keeping current commit")
+			return g.current, nil
+		}
+		return nil, err
+	}
+
+	// Keep the previous clone around for updates still running on it; drop the one before.
+	if g.prevDir != "" {
+		os.RemoveAll(g.prevDir)
+	}
+	g.prevDir, g.dir, g.current = g.dir, dir, st
+	log.Info().Str("url", g.src.URL).Str("commit", st.commit).Msg("Checked out program")
+	return st, nil
+}
+
+// replay re-applies the config written so far to a new clone, whose Pulumi.<stack>.yaml
+// only has the values committed to the repository. Called with g.mu held.
+func (g *gitCheckout) replay(ctx context.Context, st *gitStack) error {
+	for _, w := range g.written {
+		if err := st.stack.SetConfigWithOptions(ctx, w.key, w.value, w.opts); err != nil {
+			return fmt.Errorf("failed to carry over config %s: %w", w.key, err)
+		}
+	}
+	return nil
+}
+
+// clone checks out the source into a new directory.
+func (g *gitCheckout) clone(ctx context.Context) (*gitStack, string, error) {
+	dir, err := os.MkdirTemp("", "pulumiscale-git-")
+	if err != nil {
+		return nil, "", err
+	}
+	fail := func(err error) (*gitStack, string, error) {
+		os.RemoveAll(dir)
+		return nil, "", err
+	}
+
+	ws, err := auto.NewLocalWorkspace(ctx, auto.WorkDir(dir), auto.Repo(auto.GitRepo{
+		URL:         g.src.URL,
+		Branch:      g.src.Branch,
+		CommitHash:  g.src.Commit,
+		ProjectPath: g.src.ProjectPath,
+		Auth:        g.src.Auth,
+	}))
+	if err != nil {
+		return fail(err)
+	}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fail(fmt.Errorf("failed to open clone: %w", err))
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fail(fmt.Errorf("failed to resolve HEAD: %w", err))
+	}
+	s, err := auto.UpsertStack(ctx, g.stackName, ws)
+	if err != nil {
+		return fail(err)
+	}
+	return &gitStack{stack: &s, commit: head.Hash().String(), checkout: g}, dir, nil
+}
+
+// stackCommit returns the commit a stack's program was checked out at, if it came from git.
+func stackCommit(s stack) string {
+	if c, ok := s.(interface{ Commit() string }); ok {
+		return c.Commit()
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestGitCheckoutPull(t *testing.T) {
+	ctx := context.Background()
+	commits := []string{"aaa", "aaa", "bbb"}
+	var clones []string
+	var cloneErr error
+	co := &gitCheckout{
+		src:     GitSource{URL: "https://example.com/infra.git", PullInterval: time.Hour},
+		written: make(map[string]configWrite),
+	}
+	co.cloneFn = func(context.Context) (*gitStack, string, error) {
+		if cloneErr != nil {
+			return nil, "", cloneErr
+		}
+		dir := t.TempDir()
+		clones = append(clones, dir)
+		commit := commits[0]
+		commits = commits[1:]
+		return &gitStack{commit: commit, checkout: co}, dir, nil
+	}
+	commitOf := func() string {
+		t.Helper()
+		s, err := co.open(ctx)
+		if err != nil {
+			t.Fatalf("open() error = %v", err)
+		}
+		return stackCommit(s)
+	}
+
+	if got := commitOf(); got != "aaa" {
+		t.Fatalf("commit = %s, want aaa", got)
+	}
+	// Within PullInterval the clone is reused.
+	commitOf()
+	if len(clones) != 1 {
+		t.Fatalf("cloned %d times, want 1", len(clones))
+	}
+
+	// Same commit after the interval: the new clone is discarded.
+	co.pulledAt = time.Now().Add(-2 * time.Hour)
+	if got := commitOf(); got != "aaa" {
+		t.Errorf("commit = %s, want aaa", got)
+	}
+	if _, err := os.Stat(clones[1]); !os.IsNotExist(err) {
+		t.Errorf("duplicate clone %s was not removed", clones[1])
+	}
+
+	// A new commit replaces the current clone.
+	co.pulledAt = time.Now().Add(-2 * time.Hour)
+	if got := commitOf(); got != "bbb" {
+		t.Errorf("commit = %s, want bbb", got)
+	}
+
+	// A failing pull keeps serving the current commit.
+	cloneErr = errors.New("network unreachable")
+	co.pulledAt = time.Now().Add(-2 * time.Hour)
+	if got := commitOf(); got != "bbb" {
+		t.Errorf("commit = %s, want bbb", got)
+	}
+}
+
+func TestGitCheckoutKeepsWrittenConfig(t *testing.T) {
+	ctx := context.Background()
+	commits := []string{"aaa", "bbb"}
+	co := &gitCheckout{
+		src:     GitSource{URL: "https://example.com/infra.git", PullInterval: time.Hour},
+		written: make(map[string]configWrite),
+	}
+	co.cloneFn = func(context.Context) (*gitStack, string, error) {
+		// Every clone starts from the config committed to the repository.
+		fs := newFakeStack(map[string]string{"workerCount": "2", "maxSize": "4"})
+		commit := commits[0]
+		commits = commits[1:]
+		return &gitStack{stack: fs, commit: commit, checkout: co}, t.TempDir(), nil
+	}
+
+	s, err := co.open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetConfigWithOptions(ctx, "workerCount", auto.ConfigValue{Value: "5", Secret: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveConfigWithOptions(ctx, "maxSize", nil); err != nil {
+		t.Fatal(err)
+	}
+	// A preview's staged copy isn't the clone's config and isn't replayed.
+	if err := s.SetConfigWithOptions(ctx, "minSize", auto.ConfigValue{Value: "1"}, &auto.ConfigOptions{ConfigFile: "staged.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	co.pulledAt = time.Now().Add(-2 * time.Hour)
+	s, err = co.open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stackCommit(s) != "bbb" {
+		t.Fatalf("commit = %s, want bbb", stackCommit(s))
+	}
+	if got, _ := s.GetConfigWithOptions(ctx, "workerCount", nil); got.Value != "5" || !got.Secret {
+		t.Errorf("workerCount after pull = %+v, want the secret value written before it", got)
+	}
+	// Only writes are replayed; the key removed by a rollback comes back from the repository.
+	if got, _ := s.GetConfigWithOptions(ctx, "maxSize", nil); got.Value != "4" {
+		t.Errorf("maxSize after pull = %+v, want the committed 4", got)
+	}
+	if got, err := s.GetConfigWithOptions(ctx, "minSize", nil); err == nil {
+		t.Errorf("minSize after pull = %+v, want the staged value left out", got)
+	}
+}
+
+func TestGitCheckoutPinned(t *testing.T) {
+	co := &gitCheckout{src: GitSource{Commit: "ccc", PullInterval: time.Nanosecond}}
+	calls := 0
+	co.cloneFn = func(context.Context) (*gitStack, string, error) {
+		calls++
+		return &gitStack{commit: "ccc", checkout: co}, t.TempDir(), nil
+	}
+	for range 3 {
+		if _, err := co.open(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if calls != 1 {
+		t.Errorf("pinned source cloned %d times, want 1", calls)
+	}
+}
+
+// commitFakeStack is a fakeStack checked out at a fixed commit.
+type commitFakeStack struct{ *fakeStack }
+
+func (commitFakeStack) Commit() string { return "0123abc" }
+
+func TestApplyRecordsCommit(t *testing.T) {
+	fs := newFakeStack(map[string]string{"workerCount": "2"})
+	sm := &StateManager{open: func(context.Context) (stack, error) { return commitFakeStack{fs}, nil }}
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:asg", ConfigKey: "workerCount", Max: 10}
+
+	res, err := sm.Apply(context.Background(), rule, IntValue(3))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if res.Commit != "0123abc" {
+		t.Errorf("Commit = %q, want 0123abc", res.Commit)
+	}
+}
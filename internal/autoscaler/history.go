@@ -0,0 +1,122 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// UpdateMessagePrefix starts the message of every update the scaler runs, so its
+// updates can be told apart from other updates in the stack's history.
+const UpdateMessagePrefix = "pulumiscale: "
+
+// scaleMessagePattern matches messages written by scaleMessage.
+var scaleMessagePattern = regexp.MustCompile(`^` + UpdateMessagePrefix + `scale (\S+) from (.+?) to (.+?)(?: via (.+))?$`)
+
+// ScalerUpdate is a stack update the scaler ran for a pool, read back from the stack's history.
+type ScalerUpdate struct {
+	Version    int       `json:"version"`
+	Pool       string    `json:"pool"`
+	Previous   Value     `json:"previous"`
+	Target     Value     `json:"target"`
+	Source     string    `json:"source,omitempty"`
+	Result     string    `json:"result"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitzero"`
+	Message    string    `json:"message"`
+}
+
+// scaleMessage is the update message of a targeted up that scales pool.
+// Strings are quoted so the values can be parsed back.
+func scaleMessage(pool string, previous, target Value, source string) string {
+	msg := fmt.Sprintf("%sscale %s from %s to %s", UpdateMessagePrefix, pool, messageValue(previous), messageValue(target))
+	if source != "" {
+		msg += " via " + source
+	}
+	return msg
+}
+
+func messageValue(v Value) string {
+	switch {
+	case v.Secret:
+		return redacted
+	case v.Type == "":
+		return "unknown"
+	case v.Type == ValueString:
+		return strconv.Quote(v.Text)
+	default:
+		return v.Raw()
+	}
+}
+
+func parseMessageValue(s string) Value {
+	switch s {
+	case redacted:
+		return Value{Secret: true}
+	case "unknown":
+		return Value{}
+	}
+	if text, err := strconv.Unquote(s); err == nil {
+		return StringValue(text)
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return IntValue(n)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return FloatValue(f)
+	}
+	return StringValue(s)
+}
+
+// parseScaleMessage reads an update message written by scaleMessage.
+func parseScaleMessage(msg string) (ScalerUpdate, bool) {
+	m := scaleMessagePattern.FindStringSubmatch(msg)
+	if m == nil {
+		return ScalerUpdate{}, false
+	}
+	return ScalerUpdate{
+		Pool:     m[1],
+		Previous: parseMessageValue(m[2]),
+		Target:   parseMessageValue(m[3]),
+		Source:   m[4],
+		Message:  msg,
+	}, true
+}
+
+type sourceKey struct{}
+
+// withSource records the intent source that the update message of an Apply names.
+func withSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func sourceFrom(ctx context.Context) string {
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
+
+// ScalerUpdates returns the updates the scaler ran for pool, newest first, among the
+// stack's last depth updates. An empty pool returns the updates of every pool.
+func (sm *StateManager) ScalerUpdates(ctx context.Context, pool string, depth int) ([]ScalerUpdate, error) {
+	updates, err := sm.History(ctx, depth)
+	if err != nil {
+		return nil, err
+	}
+	var out []ScalerUpdate
+	for _, u := range updates {
+		su, ok := parseScaleMessage(u.Message)
+		if !ok || (pool != "" && su.Pool != pool) {
+			continue
+		}
+		su.Version = u.Version
+		su.Result = u.Result
+		su.StartedAt, _ = time.Parse(time.RFC3339, u.StartTime)
+		if u.EndTime != nil {
+			su.FinishedAt, _ = time.Parse(time.RFC3339, *u.EndTime)
+		}
+		out = append(out, su)
+	}
+	return out, nil
+}
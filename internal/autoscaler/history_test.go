@@ -0,0 +1,73 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestScaleMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name             string
+		previous, target Value
+		source           string
+		want             string
+	}{
+		{"int", IntValue(3), IntValue(5), "cloudwatch", "pulumiscale: scale workers from 3 to 5 via cloudwatch"},
+		{"float without source", FloatValue(0.5), FloatValue(1.25), "", "pulumiscale: scale workers from 0.5 to 1.25"},
+		{"string", StringValue("m5.large"), StringValue("m5 xlarge to go"), "cli", `pulumiscale: scale workers from "m5.large" to "m5 xlarge to go" via cli`},
+		{"secret and unknown", Value{}, Value{Type: ValueInt, Number: 9, Secret: true}, "", "pulumiscale: scale workers from unknown to [secret]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := scaleMessage("workers", tt.previous, tt.target, tt.source)
+			if msg != tt.want {
+				t.Fatalf("scaleMessage() = %q, want %q", msg, tt.want)
+			}
+			got, ok := parseScaleMessage(msg)
+			if !ok || got.Pool != "workers" || got.Source != tt.source {
+				t.Fatalf("parseScaleMessage() = %+v, %v", got, ok)
+			}
+			if got.Previous.String() != tt.previous.String() || got.Target.String() != tt.target.String() {
+				t.Errorf("parsed %v -> %v, want %v -> %v", got.Previous, got.Target, tt.previous, tt.target)
+			}
+		})
+	}
+	if _, ok := parseScaleMessage("pulumiscale: reconcile"); ok {
+		t.Error("reconcile message parsed as a scale")
+	}
+}
+
+func TestScalerUpdates(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	sm := newFakeStateManager(fs)
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+
+	if _, err := sm.Apply(withSource(ctx, "cloudwatch"), rule, IntValue(5)); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := "pulumiscale: scale workers from 3 to 5 via cloudwatch"; fs.upOpts.Message != want {
+		t.Errorf("up message = %q, want %q", fs.upOpts.Message, want)
+	}
+
+	end := "2025-01-01T03:02:30Z"
+	fs.history = []auto.UpdateSummary{
+		{Version: 4, Message: "pulumiscale: scale api from 1 to 2", Result: "succeeded"},
+		{Version: 3, Message: fs.upOpts.Message, Result: "succeeded", StartTime: "2025-01-01T03:00:00Z", EndTime: &end},
+		{Version: 2, Message: "manual deploy", Result: "succeeded"},
+		{Version: 1, Message: "pulumiscale: reconcile", Result: "succeeded"},
+	}
+	updates, err := sm.ScalerUpdates(ctx, "workers", 10)
+	if err != nil || len(updates) != 1 {
+		t.Fatalf("ScalerUpdates() = %+v, %v; want the one workers update", updates, err)
+	}
+	u := updates[0]
+	if u.Version != 3 || u.Source != "cloudwatch" || !u.Target.Equal(IntValue(5)) || u.FinishedAt.Sub(u.StartedAt).Seconds() != 150 {
+		t.Errorf("update = %+v", u)
+	}
+	if all, _ := sm.ScalerUpdates(ctx, "", 10); len(all) != 2 {
+		t.Errorf("ScalerUpdates(all pools) = %d updates, want 2", len(all))
+	}
+}
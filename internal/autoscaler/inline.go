@@ -0,0 +1,37 @@
+package autoscaler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// NewInlineStateManager manages a stack whose program is a Go function running in this process.
+//
+// The workspace is created on first use and kept for the life of the StateManager, because an
+// inline workspace stores stack config in its (by default temporary) work directory. Pass
+// auto.WorkDir to keep that config across restarts.
+func NewInlineStateManager(stackName, projectName string, program pulumi.RunFunc, opts ...auto.LocalWorkspaceOption) *StateManager {
+	var (
+		mu     sync.Mutex
+		cached *auto.Stack
+	)
+	return &StateManager{
+		StackName: stackName,
+		open: func(ctx context.Context) (stack, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if cached != nil {
+				return cached, nil
+			}
+			s, err := auto.UpsertStackInlineSource(ctx, stackName, projectName, program, opts...)
+			if err != nil {
+				return nil, err
+			}
+			cached = &s
+			return cached, nil
+		},
+	}
+}
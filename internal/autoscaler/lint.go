@@ -0,0 +1,134 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// Linter runs the full offline validation behind `pulumiscale validate`: the contract
+// checks done by ConfigLoader plus checks against the stack's current state and config.
+type Linter struct {
+	Loader *ConfigLoader
+
+	// (Optional) Stack to check the rules against. Nil limits the lint to the contract.
+	State *StateManager
+}
+
+// LintReport is the machine-readable result of a lint.
+type LintReport struct {
+	Valid  bool             `json:"valid"`
+	Stack  string           `json:"stack,omitempty"`
+	Pools  []PoolLintResult `json:"pools"`
+	Errors ValidationErrors `json:"errors"`
+}
+
+// PoolLintResult records what was checked for one pool.
+type PoolLintResult struct {
+	Pool    string `json:"pool"`
+	Current Value  `json:"current"`
+	Valid   bool   `json:"valid"`
+}
+
+// Lint loads and checks the rules. The returned error is reserved for failures that
+// prevent linting at all (an unreadable source or stack); rule problems are reported
+// in LintReport.Errors.
+func (l *Linter) Lint(ctx context.Context) (LintReport, error) {
+	report := LintReport{Pools: []PoolLintResult{}, Errors: ValidationErrors{}}
+	if l.State != nil {
+		report.Stack = l.State.StackName
+	}
+
+	rules, err := l.Loader.LoadRules(ctx)
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		report.Errors = verrs
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	if l.State != nil {
+		resources, err := l.State.Resources(ctx)
+		if err != nil {
+			return report, err
+		}
+		urns := make(map[string]bool, len(resources))
+		for _, res := range resources {
+			urns[string(res.URN)] = true
+		}
+		s, err := l.State.stack(ctx)
+		if err != nil {
+			return report, err
+		}
+		source := "stack:" + l.State.StackName
+		for _, name := range slices.Sorted(maps.Keys(rules)) {
+			current, errs := lintRule(ctx, s, rules[name], urns)
+			report.Errors = append(report.Errors, errs.withPrefix("/"+jsonPointerEscape(name)).withSource(source)...)
+			report.Pools = append(report.Pools, PoolLintResult{Pool: name, Current: current, Valid: len(errs) == 0})
+		}
+	} else {
+		for _, name := range slices.Sorted(maps.Keys(rules)) {
+			report.Pools = append(report.Pools, PoolLintResult{Pool: name, Valid: true})
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+// lintRule checks a rule against the stack: its URNs exist in state, its config keys exist
+// and parse as the declared types, and the current value is within the rule's guardrails.
+func lintRule(ctx context.Context, s stack, rule ScalingRule, urns map[string]bool) (Value, ValidationErrors) {
+	var errs ValidationErrors
+	add := func(ptr, format string, args ...any) {
+		errs = append(errs, FieldError{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+	checkExists := func(ptr string, list []string, indexed bool) {
+		for i, urn := range list {
+			p := ptr
+			if indexed {
+				p = fmt.Sprintf("%s/%d", ptr, i)
+			}
+			if !urns[urn] {
+				add(p, "resource %s does not exist in the stack", urn)
+			}
+		}
+	}
+	if rule.TargetURN != "" {
+		checkExists("/targetUrn", []string{rule.TargetURN}, false)
+	}
+	checkExists("/targetUrns", rule.TargetURNs, true)
+	checkExists("/alsoTarget", rule.AlsoTarget, true)
+	if cf := rule.CurrentFrom; cf != nil && cf.Resource != "" {
+		checkExists("/currentFrom/resource", []string{cf.Resource}, false)
+	}
+
+	current, _, err := getValue(ctx, s, rule)
+	switch {
+	case err != nil:
+		add("/configKey", "config key %s: %v", rule.ConfigKey, err)
+	case rule.valueType() == ValueString:
+		if len(rule.Allowed) > 0 && !slices.Contains(rule.Allowed, current.Text) {
+			add("/allowed", "current value %s is not in allowed", current)
+		}
+	case current.Number < rule.Min || current.Number > rule.Max:
+		add("/configKey", "current value %s is outside [%v, %v]", current, rule.Min, rule.Max)
+	}
+
+	for i, dk := range rule.DerivedKeys {
+		cfg, err := s.GetConfigWithOptions(ctx, dk.ConfigKey, &auto.ConfigOptions{Path: dk.Path})
+		if err == nil {
+			_, err = parseValue(dk.Type, cfg.Value)
+		}
+		if err != nil {
+			add(fmt.Sprintf("/derivedKeys/%d/configKey", i), "config key %s: %v", dk.ConfigKey, err)
+		}
+	}
+	return current, errs
+}
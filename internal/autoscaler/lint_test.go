@@ -0,0 +1,100 @@
+package autoscaler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestLinter(t *testing.T) {
+	const (
+		asgURN   = "urn:pulumi:dev::app::aws:autoscaling/group:Group::workers"
+		alarmURN = "urn:pulumi:dev::app::aws:cloudwatch/metricAlarm:MetricAlarm::alarm"
+	)
+	rule := func(extra map[string]any) map[string]any {
+		r := map[string]any{"targetUrn": asgURN, "configKey": "workerCount", "min": 1, "max": 10}
+		for k, v := range extra {
+			r[k] = v
+		}
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		rule     map[string]any
+		config   map[string]string
+		pointers []string
+	}{
+		{
+			name:   "valid",
+			rule:   rule(map[string]any{"alsoTarget": []string{alarmURN}}),
+			config: map[string]string{"workerCount": "3"},
+		},
+		{
+			name:     "missing resource",
+			rule:     rule(map[string]any{"alsoTarget": []string{"urn:pulumi:dev::app::aws:sns/topic:Topic::gone"}}),
+			config:   map[string]string{"workerCount": "3"},
+			pointers: []string{"/workers/alsoTarget/0"},
+		},
+		{
+			name:     "missing config key",
+			rule:     rule(nil),
+			pointers: []string{"/workers/configKey"},
+		},
+		{
+			name:     "wrong type",
+			rule:     rule(nil),
+			config:   map[string]string{"workerCount": "many"},
+			pointers: []string{"/workers/configKey"},
+		},
+		{
+			name:     "current out of range",
+			rule:     rule(nil),
+			config:   map[string]string{"workerCount": "12"},
+			pointers: []string{"/workers/configKey"},
+		},
+		{
+			name: "derived key missing",
+			rule: rule(map[string]any{"derivedKeys": []map[string]any{
+				{"configKey": "maxSize", "value": "desired + 2"},
+			}}),
+			config:   map[string]string{"workerCount": "3"},
+			pointers: []string{"/workers/derivedKeys/0/configKey"},
+		},
+		{
+			name:     "contract errors skip stack checks",
+			rule:     rule(map[string]any{"cooldownSeconds": 60}),
+			pointers: []string{"/workers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeStack(tt.config)
+			fs.outputs = auto.OutputMap{"pulumiscale": {Value: map[string]any{"workers": tt.rule}}}
+			fs.resources = []apitype.ResourceV3{{URN: resource.URN(asgURN)}, {URN: resource.URN(alarmURN)}}
+
+			linter := &Linter{
+				Loader: &ConfigLoader{Sources: []RuleSource{&OutputSource{Key: "pulumiscale", open: openFake(fs)}}},
+				State:  newFakeStateManager(fs),
+			}
+			report, err := linter.Lint(context.Background())
+			if err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+			if report.Valid != (len(tt.pointers) == 0) {
+				t.Errorf("Valid = %v, errors %v", report.Valid, report.Errors)
+			}
+			var got []string
+			for _, fe := range report.Errors {
+				got = append(got, fe.Pointer)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.pointers, ",") {
+				t.Errorf("pointers = %v, want %v (%v)", got, tt.pointers, report.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package autoscaler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// logFrom returns the logger attached to ctx, which carries the fields of the job or check
+// being run (pool, job and request IDs), or the global logger when there is none.
+func logFrom(ctx context.Context) *zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return l
+	}
+	return &log.Logger
+}
+
+// newJobID returns a random identifier for one processed intent.
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
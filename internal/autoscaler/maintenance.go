@@ -0,0 +1,106 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/rs/zerolog/log"
+)
+
+// PoolBaseline is a pool's config value as checked by Reconcile.
+type PoolBaseline struct {
+	Pool     string `json:"pool"`
+	Previous Value  `json:"previous"`
+	Value    Value  `json:"value"`
+	Clamped  bool   `json:"clamped"`
+}
+
+// ReconcileResult is the outcome of a maintenance run.
+type ReconcileResult struct {
+	Pools   []PoolBaseline `json:"pools"`
+	Changes map[string]int `json:"changes,omitempty"`
+}
+
+// Reconcile restores the stack's baseline before scaling starts (maintenance mode).
+// Every pool's config value is checked against its guardrails and clamped into [Min, Max]
+// if needed, then a full refresh and up deploys the result. A pool whose value is missing,
+// unparsable or not allowed fails the run, since the baseline can't be trusted.
+func (sm *StateManager) Reconcile(ctx context.Context, rules map[string]ScalingRule) (ReconcileResult, error) {
+	result := ReconcileResult{Pools: []PoolBaseline{}}
+	s, err := sm.stack(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(rules)) {
+		rule := rules[name]
+		current, raw, err := getValue(ctx, s, rule)
+		if err != nil {
+			return result, fmt.Errorf("pool %s: %w", name, err)
+		}
+		baseline := PoolBaseline{Pool: name, Previous: current, Value: current}
+
+		if rule.valueType() == ValueString {
+			if len(rule.Allowed) > 0 && !slices.Contains(rule.Allowed, current.Text) {
+				return result, fmt.Errorf("pool %s: value %q is not allowed", name, current.Text)
+			}
+			result.Pools = append(result.Pools, baseline)
+			continue
+		}
+
+		clamped := NumberValue(rule.valueType(), math.Max(rule.Min, math.Min(rule.Max, current.Number)))
+		clamped.Secret = current.Secret
+		if !clamped.Equal(current) {
+			if err := persistBaseline(ctx, s, rule, clamped, current, raw.Secret); err != nil {
+				return result, fmt.Errorf("pool %s: %w", name, err)
+			}
+			log.Warn().
+				Str("pool", name).
+				Stringer("previous", current).
+				Stringer("value", clamped).
+				Msg("Config value outside guardrails; clamped")
+			baseline.Value = clamped
+			baseline.Clamped = true
+		}
+		result.Pools = append(result.Pools, baseline)
+	}
+
+	log.Info().Msg("Running refresh and full update...")
+	up, err := sm.Up(ctx, optup.Refresh(), optup.Message(UpdateMessagePrefix+"reconcile"))
+	if err != nil {
+		return result, fmt.Errorf("up failed: %w", err)
+	}
+	if up.Summary.ResourceChanges != nil {
+		result.Changes = *up.Summary.ResourceChanges
+	}
+	return result, nil
+}
+
+// persistBaseline writes a clamped value, and the derived keys that follow it, to the stack config.
+// Keys that are secret stay secret.
+func persistBaseline(ctx context.Context, s stack, rule ScalingRule, value, current Value, secret bool) error {
+	secret = secret || rule.Secret
+	if err := s.SetConfigWithOptions(ctx, rule.ConfigKey, auto.ConfigValue{Value: value.Raw(), Secret: secret}, rule.configOptions()); err != nil {
+		return fmt.Errorf("failed to set config %s: %w", rule.ConfigKey, err)
+	}
+	derived, err := rule.deriveValues(value, current)
+	if err != nil {
+		return err
+	}
+	for _, dk := range rule.DerivedKeys {
+		v := derived[dk.ConfigKey]
+		opts := &auto.ConfigOptions{Path: dk.Path}
+		if prev, err := s.GetConfigWithOptions(ctx, dk.ConfigKey, opts); err == nil {
+			v.Secret = v.Secret || prev.Secret
+		}
+		if err := s.SetConfigWithOptions(ctx, dk.ConfigKey, auto.ConfigValue{Value: v.Raw(), Secret: v.Secret}, opts); err != nil {
+			return fmt.Errorf("failed to set config %s: %w", dk.ConfigKey, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	rules := map[string]ScalingRule{
+		"workers": {
+			PoolName:    "workers",
+			ConfigKey:   "workerCount",
+			Min:         2,
+			Max:         10,
+			DerivedKeys: []DerivedKey{{ConfigKey: "maxSize", Value: "desired + 2"}},
+		},
+		"api":   {PoolName: "api", ConfigKey: "apiCount", Min: 1, Max: 5},
+		"share": {PoolName: "share", ConfigKey: "share", Type: ValueFloat, Min: 0.5, Max: 2},
+	}
+
+	fs := newFakeStack(map[string]string{"workerCount": "40", "maxSize": "42", "apiCount": "3", "share": "0.1"})
+	res, err := newFakeStateManager(fs).Reconcile(ctx, rules)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	want := map[string]string{"workerCount": "10", "maxSize": "12", "apiCount": "3", "share": "0.5"}
+	for k, v := range want {
+		if fs.config[k].Value != v {
+			t.Errorf("%s = %s, want %s", k, fs.config[k].Value, v)
+		}
+	}
+	clamped := map[string]bool{}
+	for _, p := range res.Pools {
+		clamped[p.Pool] = p.Clamped
+	}
+	if !clamped["workers"] || clamped["api"] || !clamped["share"] {
+		t.Errorf("clamped = %v", clamped)
+	}
+	if fs.upCalls != 1 || !fs.upOpts.Refresh || len(fs.upOpts.Target) != 0 {
+		t.Errorf("expected one full refresh+up, got %d calls with %+v", fs.upCalls, fs.upOpts)
+	}
+
+	t.Run("secret derived keys stay secret", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"workerCount": "40", "apiCount": "3", "share": "1"})
+		fs.config["maxSize"] = auto.ConfigValue{Value: "42", Secret: true}
+		if _, err := newFakeStateManager(fs).Reconcile(ctx, rules); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if got := fs.config["maxSize"]; got.Value != "12" || !got.Secret {
+			t.Errorf("maxSize = %+v, want secret 12", got)
+		}
+	})
+
+	t.Run("missing config fails before up", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"apiCount": "3"})
+		if _, err := newFakeStateManager(fs).Reconcile(ctx, rules); err == nil {
+			t.Error("expected error")
+		}
+		if fs.upCalls != 0 {
+			t.Errorf("up ran %d times", fs.upCalls)
+		}
+	})
+}
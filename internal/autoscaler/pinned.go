@@ -0,0 +1,89 @@
+package autoscaler
+
+import (
+	"context"
+	"time"
+)
+
+// PinnedState describes a numeric pool held at a guardrail by intents asking to go past it,
+// e.g. demand exceeding the capacity allowed by max.
+type PinnedState struct {
+	Bound ClampBound `json:"bound"`
+	Since time.Time  `json:"since"`
+
+	// Requested is the unclamped target of the latest intent, or 0 for secret pools.
+	Requested float64 `json:"requested"`
+
+	// Alerted is set once the pool has been pinned for longer than its alert duration.
+	Alerted bool `json:"alerted"`
+}
+
+// pinnedAfter returns how long rule's pool may stay pinned before an alert, or 0 if it never alerts.
+func (e *Engine) pinnedAfter(rule ScalingRule) time.Duration {
+	if rule.PinnedAlertSeconds > 0 {
+		return time.Duration(rule.PinnedAlertSeconds) * time.Second
+	}
+	return e.PinnedAfter
+}
+
+// trackPinned updates the pool's pinned state from a finished job and marks result with
+// it. A pool is pinned from the first job clamped to a bound until a job that isn't clamped,
+// or is clamped to the other bound. Jobs that didn't evaluate the target (cooldowns, pauses,
+// failures, dry runs) leave the state as it is.
+func (e *Engine) trackPinned(ctx context.Context, rule ScalingRule, result *JobResult) {
+	if rule.PoolName == "" || rule.valueType() == ValueString {
+		return
+	}
+	if result.Status != JobSucceeded && result.Skipped != SkipUnchanged {
+		return
+	}
+	now := result.FinishedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var pinned PinnedState
+	e.updatePool(rule.PoolName, func(st *poolState) {
+		if result.Clamped == "" {
+			st.pinned = nil
+			return
+		}
+		if st.pinned == nil || st.pinned.Bound != result.Clamped {
+			st.pinned = &PinnedState{Bound: result.Clamped, Since: now}
+		}
+		st.pinned.Requested = result.Requested
+		result.PinnedSince = st.pinned.Since
+
+		if after := e.pinnedAfter(rule); after > 0 && !st.pinned.Alerted && now.Sub(st.pinned.Since) >= after {
+			st.pinned.Alerted = true
+			result.PinnedAlert = true
+		}
+		pinned = *st.pinned
+	})
+
+	if result.PinnedAlert {
+		limit := rule.Max
+		if pinned.Bound == ClampMin {
+			limit = rule.Min
+		}
+		event := logFrom(ctx).Warn().
+			Str("bound", string(pinned.Bound)).
+			Float64("limit", limit)
+		if !rule.Secret {
+			event = event.Float64("requested", pinned.Requested)
+		}
+		event.Time("since", pinned.Since).
+			Msg("Pool is pinned at its guardrail; intents keep asking to go past it")
+	}
+}
+
+// Pinned returns the pinned state of pool, or false if it isn't pinned.
+func (e *Engine) Pinned(pool string) (PinnedState, bool) {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	st, ok := e.pools[pool]
+	if !ok || st.pinned == nil {
+		return PinnedState{}, false
+	}
+	return *st.pinned, true
+}
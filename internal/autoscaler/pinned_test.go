@@ -0,0 +1,72 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestTrackPinned(t *testing.T) {
+	rule := ScalingRule{PoolName: "workers", Min: 1, Max: 10, PinnedAlertSeconds: 600}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, nil)
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	job := func(offset time.Duration, status JobStatus, skipped SkipReason, clamped ClampBound, requested float64) JobResult {
+		result := JobResult{Pool: "workers", Status: status, Skipped: skipped, Clamped: clamped, Requested: requested, FinishedAt: base.Add(offset)}
+		e.trackPinned(context.Background(), rule, &result)
+		return result
+	}
+
+	if r := job(0, JobSucceeded, "", ClampMax, 14); !r.PinnedSince.Equal(base) || r.PinnedAlert {
+		t.Errorf("first clamp = %+v, want pinned since now without an alert", r)
+	}
+	// Cooldowns and failures don't evaluate the target, so they neither extend nor end the pin.
+	job(5*time.Minute, JobSkipped, SkipCooldown, "", 0)
+	job(6*time.Minute, JobFailed, "", "", 0)
+	if r := job(9*time.Minute, JobSkipped, SkipUnchanged, ClampMax, 18); r.PinnedAlert {
+		t.Errorf("clamp after 9m = %+v, want no alert before 10m", r)
+	}
+	r := job(11*time.Minute, JobSkipped, SkipUnchanged, ClampMax, 20)
+	if !r.PinnedAlert || !r.PinnedSince.Equal(base) {
+		t.Errorf("clamp after 11m = %+v, want an alert pinned since the first clamp", r)
+	}
+	if p, ok := e.Pinned("workers"); !ok || p.Bound != ClampMax || p.Requested != 20 || !p.Alerted {
+		t.Errorf("Pinned() = %+v, %v; want max, requested 20, alerted", p, ok)
+	}
+	if r := job(20*time.Minute, JobSkipped, SkipUnchanged, ClampMax, 20); r.PinnedAlert {
+		t.Error("alert raised again while still pinned")
+	}
+
+	// Switching bounds restarts the pin; an unclamped job ends it.
+	if r := job(21*time.Minute, JobSucceeded, "", ClampMin, -3); !r.PinnedSince.Equal(base.Add(21 * time.Minute)) {
+		t.Errorf("clamp to min = %+v, want a new pin", r)
+	}
+	job(22*time.Minute, JobSucceeded, "", "", 0)
+	if _, ok := e.Pinned("workers"); ok {
+		t.Error("pool still pinned after an unclamped scale")
+	}
+}
+
+func TestPinnedAfterDefault(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "10"})
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, newFakeStateManager(fs))
+
+	// Without PinnedAfter or pinnedAlert the state is tracked but never alerts.
+	result := e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionDelta, Value: 5})
+	if result.Skipped != SkipUnchanged || result.PinnedSince.IsZero() || result.PinnedAlert {
+		t.Errorf("result = %+v, want an unchanged skip pinned without an alert", result)
+	}
+	if status, _ := e.PoolStatus(ctx, "workers"); status.Pinned == nil || status.Pinned.Requested != 15 {
+		t.Errorf("status pinned = %+v, want requested 15", status.Pinned)
+	}
+
+	e.PinnedAfter = time.Nanosecond
+	result = e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionDelta, Value: 5})
+	if !result.PinnedAlert {
+		t.Errorf("result = %+v, want an alert once PinnedAfter has passed", result)
+	}
+}
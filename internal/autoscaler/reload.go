@@ -0,0 +1,141 @@
+package autoscaler
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RuleLoader loads the current set of scaling rules.
+type RuleLoader interface {
+	LoadRules(ctx context.Context) (map[string]ScalingRule, error)
+}
+
+// RuleDiff lists the pools that differ between two rule sets.
+type RuleDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// Empty reports whether the rule sets were identical.
+func (d RuleDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffRules compares two rule sets by pool name. Pool lists are sorted.
+func DiffRules(old, updated map[string]ScalingRule) RuleDiff {
+	diff := RuleDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for name, rule := range updated {
+		prev, ok := old[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case !reflect.DeepEqual(prev, rule):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := updated[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	slices.Sort(diff.Changed)
+	return diff
+}
+
+// Reloader keeps an Engine's rules in sync with a RuleLoader.
+// A failed load keeps the previous rules so a transient backend error never empties the engine.
+type Reloader struct {
+	Loader RuleLoader
+	Engine *Engine
+
+	// Interval between periodic reloads. Zero disables polling; Trigger still works.
+	Interval time.Duration
+
+	// OnChange is called with the new rules after every reload that changed something,
+	// e.g. to re-register webhook routes.
+	OnChange func(rules map[string]ScalingRule)
+
+	mu      sync.Mutex // serialises reloads
+	trigger chan struct{}
+	once    sync.Once
+}
+
+func NewReloader(loader RuleLoader, engine *Engine, interval time.Duration) *Reloader {
+	return &Reloader{
+		Loader:   loader,
+		Engine:   engine,
+		Interval: interval,
+	}
+}
+
+func (r *Reloader) triggerChan() chan struct{} {
+	r.once.Do(func() { r.trigger = make(chan struct{}, 1) })
+	return r.trigger
+}
+
+// Trigger requests an asynchronous reload from Run, e.g. on SIGHUP.
+func (r *Reloader) Trigger() {
+	select {
+	case r.triggerChan() <- struct{}{}:
+	default: // a reload is already pending
+	}
+}
+
+// Reload loads the rules, swaps them into the engine and reports what changed.
+func (r *Reloader) Reload(ctx context.Context) (RuleDiff, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules, err := r.Loader.LoadRules(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reload scaling rules. Keeping current rules.")
+		return RuleDiff{}, err
+	}
+
+	diff := DiffRules(r.Engine.RulesSnapshot(), rules)
+	if diff.Empty() {
+		log.Debug().Int("count", len(rules)).Msg("Scaling rules unchanged")
+		return diff, nil
+	}
+
+	r.Engine.SetRules(rules)
+	log.Info().
+		Strs("added", diff.Added).
+		Strs("removed", diff.Removed).
+		Strs("changed", diff.Changed).
+		Int("count", len(rules)).
+		Msg("Reloaded scaling rules")
+	if r.OnChange != nil {
+		r.OnChange(rules)
+	}
+	return diff, nil
+}
+
+// Run reloads on every Interval tick and on Trigger until ctx is done.
+func (r *Reloader) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if r.Interval > 0 {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	trigger := r.triggerChan()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-trigger:
+		}
+		r.Reload(ctx)
+	}
+}
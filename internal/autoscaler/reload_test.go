@@ -0,0 +1,78 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type fakeLoader struct {
+	rules map[string]ScalingRule
+	err   error
+}
+
+func (fl *fakeLoader) LoadRules(context.Context) (map[string]ScalingRule, error) {
+	return fl.rules, fl.err
+}
+
+func TestDiffRules(t *testing.T) {
+	old := map[string]ScalingRule{
+		"a": {PoolName: "a", Max: 1},
+		"b": {PoolName: "b", Max: 1},
+		"c": {PoolName: "c", Max: 1},
+	}
+	updated := map[string]ScalingRule{
+		"a": {PoolName: "a", Max: 1},
+		"b": {PoolName: "b", Max: 2},
+		"d": {PoolName: "d", Max: 1},
+	}
+
+	diff := DiffRules(old, updated)
+	if got := fmt.Sprint(diff.Added, diff.Removed, diff.Changed); got != "[d] [c] [b]" {
+		t.Errorf("DiffRules() = %s, want [d] [c] [b]", got)
+	}
+	if !DiffRules(old, old).Empty() {
+		t.Error("DiffRules() of identical sets should be empty")
+	}
+}
+
+func TestReloader(t *testing.T) {
+	ctx := context.Background()
+	engine := NewEngine(map[string]ScalingRule{"a": {PoolName: "a"}}, nil)
+	loader := &fakeLoader{rules: map[string]ScalingRule{"a": {PoolName: "a"}, "b": {PoolName: "b"}}}
+	r := NewReloader(loader, engine, 0)
+	changes := 0
+	r.OnChange = func(map[string]ScalingRule) { changes++ }
+
+	diff, err := r.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if fmt.Sprint(diff.Added) != "[b]" {
+		t.Errorf("added = %v, want [b]", diff.Added)
+	}
+	if _, ok := engine.rule("b"); !ok {
+		t.Error("engine should have the new rule")
+	}
+	if changes != 1 {
+		t.Errorf("OnChange calls = %d, want 1", changes)
+	}
+
+	// Unchanged rules don't trigger OnChange.
+	if _, err := r.Reload(ctx); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if changes != 1 {
+		t.Errorf("OnChange calls = %d, want 1", changes)
+	}
+
+	// A failed load keeps the current rules.
+	loader.err = errors.New("backend unavailable")
+	if _, err := r.Reload(ctx); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(engine.RulesSnapshot()) != 2 {
+		t.Errorf("engine rules = %v, want the previous two", engine.RulesSnapshot())
+	}
+}
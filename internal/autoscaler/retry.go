@@ -0,0 +1,75 @@
+package autoscaler
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Clock abstracts time so backoff can be tested without real sleeps.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RetryPolicy configures the backoff used when an up hits a concurrent update.
+// Zero fields fall back to the defaults below.
+type RetryPolicy struct {
+	// Maximum number of retries after the first attempt; -1 disables retries.
+	MaxRetries int `json:"maxRetries"`
+
+	// Delay before the first retry in seconds. Doubles on every retry.
+	BaseDelaySeconds float64 `json:"baseDelay"`
+
+	// Upper bound for a single delay in seconds.
+	MaxDelaySeconds float64 `json:"maxDelay"`
+
+	// Fraction (0-1) of each delay that is randomised to spread out competing updaters.
+	Jitter float64 `json:"jitter"`
+}
+
+const (
+	defaultMaxRetries = 5
+	defaultBaseDelay  = 1 * time.Second
+	defaultMaxDelay   = 60 * time.Second
+)
+
+func (p RetryPolicy) maxRetries() int {
+	if p.MaxRetries == 0 {
+		return defaultMaxRetries
+	}
+	return max(p.MaxRetries, 0)
+}
+
+// delay returns the backoff before retry number attempt (0-based), never more than the max delay.
+// rnd returns a value in [0, 1) and is only used when Jitter is set.
+func (p RetryPolicy) delay(attempt int, rnd func() float64) time.Duration {
+	base := defaultBaseDelay
+	if p.BaseDelaySeconds > 0 {
+		base = time.Duration(p.BaseDelaySeconds * float64(time.Second))
+	}
+	maxDelay := defaultMaxDelay
+	if p.MaxDelaySeconds > 0 {
+		maxDelay = time.Duration(p.MaxDelaySeconds * float64(time.Second))
+	}
+
+	d := time.Duration(float64(base) * math.Pow(2, float64(attempt)))
+	if d > maxDelay || d <= 0 {
+		d = maxDelay
+	}
+	if p.Jitter > 0 {
+		// Spread the delay over [d*(1-jitter), d*(1+jitter)).
+		d = time.Duration(float64(d) * (1 - p.Jitter + 2*p.Jitter*rnd()))
+	}
+	return min(d, maxDelay)
+}
+
+// defaultRand is the jitter source used outside tests.
+func defaultRand() float64 {
+	return rand.Float64()
+}
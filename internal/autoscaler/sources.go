@@ -0,0 +1,212 @@
+package autoscaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleSource provides scaling rules from one place.
+// Load returns nil rules and a nil error when the source simply defines no rules
+// (e.g. the stack has no "pulumiscale" output), and an error when it can't be read.
+type RuleSource interface {
+	Name() string
+	Load(ctx context.Context) (map[string]ScalingRule, error)
+}
+
+// OutputSource reads rules from a stack output (the "pulumiscale" output by default).
+type OutputSource struct {
+	Key  string
+	open func(ctx context.Context) (stack, error)
+}
+
+// NewOutputSource reads the "pulumiscale" output of a local source stack.
+func NewOutputSource(stackName, workDir string) *OutputSource {
+	return &OutputSource{Key: "pulumiscale", open: localStack(stackName, workDir)}
+}
+
+// NewOutputSourceFor reads the "pulumiscale" output of the stack managed by sm.
+func NewOutputSourceFor(sm *StateManager) *OutputSource {
+	return &OutputSource{Key: "pulumiscale", open: sm.stack}
+}
+
+func (o *OutputSource) Name() string { return "output:" + o.Key }
+
+func (o *OutputSource) Load(ctx context.Context) (map[string]ScalingRule, error) {
+	s, err := o.open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stack: %w", err)
+	}
+
+	// Get stack outputs
+	outputs, err := s.Outputs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack outputs: %w", err)
+	}
+
+	val, ok := outputs[o.Key]
+	if !ok {
+		return nil, nil
+	}
+
+	// auto.OutputValue.Value is interface{}.
+	// Marshaling the value to JSON and then unmarshaling into our struct is a robust way to handle map[string]interface{}.
+	data, err := json.Marshal(val.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s output: %w", o.Key, err)
+	}
+	return parseRules(data)
+}
+
+// ConfigSource reads rules from a structured stack config value ("pulumiscale:rules" by default).
+// This allows rules to be managed without a deploy, via `pulumi config set --path`.
+type ConfigSource struct {
+	Key  string
+	open func(ctx context.Context) (stack, error)
+}
+
+// NewConfigSource reads "pulumiscale:rules" from a local source stack's config.
+func NewConfigSource(stackName, workDir string) *ConfigSource {
+	return &ConfigSource{Key: "pulumiscale:rules", open: localStack(stackName, workDir)}
+}
+
+// NewConfigSourceFor reads "pulumiscale:rules" from the config of the stack managed by sm,
+// wherever its program lives.
+func NewConfigSourceFor(sm *StateManager) *ConfigSource {
+	return &ConfigSource{Key: "pulumiscale:rules", open: sm.stack}
+}
+
+func (c *ConfigSource) Name() string { return "config:" + c.Key }
+
+func (c *ConfigSource) Load(ctx context.Context) (map[string]ScalingRule, error) {
+	s, err := c.open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stack: %w", err)
+	}
+
+	// GetAllConfig distinguishes a missing key from a failed read.
+	cfg, err := s.GetAllConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack config: %w", err)
+	}
+	val, ok := cfg[c.Key]
+	if !ok {
+		return nil, nil
+	}
+	return parseRules([]byte(val.Value))
+}
+
+// FileSource reads rules from a local YAML or JSON file with the same shape as the stack output.
+type FileSource struct {
+	Path string
+}
+
+func (f *FileSource) Name() string { return "file:" + f.Path }
+
+func (f *FileSource) Load(context.Context) (map[string]ScalingRule, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(f.Path)) {
+	case ".yaml", ".yml":
+		data, err = yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rules file %s: %w", f.Path, err)
+		}
+	}
+	return parseRules(data)
+}
+
+// ESCSource reads rules from a Pulumi ESC environment, under the "pulumiscale" key
+// of the environment's values by default.
+type ESCSource struct {
+	// Environment is the ESC environment reference, e.g. "myorg/platform/scaling".
+	Environment string
+	Key         string
+
+	// open runs `pulumi env open` and returns its JSON output. Nil uses the Pulumi CLI.
+	open func(ctx context.Context, env string) ([]byte, error)
+}
+
+func NewESCSource(environment string) *ESCSource {
+	return &ESCSource{Environment: environment, Key: "pulumiscale"}
+}
+
+func (e *ESCSource) Name() string { return "esc:" + e.Environment }
+
+func (e *ESCSource) Load(ctx context.Context) (map[string]ScalingRule, error) {
+	open := e.open
+	if open == nil {
+		open = openESCEnvironment
+	}
+	data, err := open(ctx, e.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ESC environment %s: %w", e.Environment, err)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse ESC environment %s: %w", e.Environment, err)
+	}
+	raw, ok := values[e.Key]
+	if !ok {
+		return nil, nil
+	}
+	return parseRules(raw)
+}
+
+// openESCEnvironment resolves an ESC environment with the Pulumi CLI.
+func openESCEnvironment(ctx context.Context, env string) ([]byte, error) {
+	cmd, err := auto.NewPulumiCommand(nil)
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr, _, err := cmd.Run(ctx, ".", nil, nil, nil, nil, "env", "open", env, "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr))
+	}
+	return []byte(stdout), nil
+}
+
+// parseRules validates a JSON object of pool name to rule against ContractSchema,
+// decodes it and fills in PoolName. Schema violations are returned as ValidationErrors.
+func parseRules(data []byte) (map[string]ScalingRule, error) {
+	if errs := validateSchema(data); len(errs) > 0 {
+		return nil, errs
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scaling rules: %w", err)
+	}
+	// "$schema" lets editors pick up the contract; it is not a pool.
+	delete(raw, "$schema")
+
+	rules := make(map[string]ScalingRule, len(raw))
+	for name, msg := range raw {
+		var rule ScalingRule
+		if err := json.Unmarshal(msg, &rule); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal scaling rule %s: %w", name, err)
+		}
+		// Populate PoolName from the map key since it's ignored in JSON (`json:"-"`)
+		rule.PoolName = name
+		rules[name] = rule
+	}
+	return rules, nil
+}
+
+// yamlToJSON converts a YAML document to JSON so it can be decoded with the rules' JSON tags.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,101 @@
+package autoscaler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func openFake(fs *fakeStack) func(context.Context) (stack, error) {
+	return func(context.Context) (stack, error) { return fs, nil }
+}
+
+func TestConfigLoaderSources(t *testing.T) {
+	ctx := context.Background()
+
+	fs := newFakeStack(map[string]string{
+		"pulumiscale:rules": `{"api": {"targetUrn": "urn:pulumi:dev::app::aws:autoscaling/group:Group::config-api", "configKey": "apiCount", "max": 4}}`,
+	})
+	fs.outputs = auto.OutputMap{"pulumiscale": {Value: map[string]any{
+		"workers": map[string]any{"targetUrn": "urn:pulumi:dev::app::aws:autoscaling/group:Group::output-workers", "configKey": "workerCount", "max": 10},
+		"api":     map[string]any{"targetUrn": "urn:pulumi:dev::app::aws:autoscaling/group:Group::output-api", "configKey": "apiCount", "max": 2},
+	}}}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rules.yaml")
+	yamlRules := "workers:\n  targetUrn: urn:pulumi:dev::app::aws:autoscaling/group:Group::file-workers\n  configKey: workerCount\n  min: 1\n  max: 20\n"
+	if err := os.WriteFile(file, []byte(yamlRules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	esc := NewESCSource("org/project/env")
+	esc.open = func(context.Context, string) ([]byte, error) {
+		return []byte(`{"pulumiscale": {"batch": {"targetUrn": "urn:pulumi:dev::app::aws:autoscaling/group:Group::esc-batch", "configKey": "batchCount", "max": 3}}, "other": 1}`), nil
+	}
+
+	loader := &ConfigLoader{}
+	loader.AddSource(&OutputSource{Key: "pulumiscale", open: openFake(fs)})
+	loader.AddSource(&ConfigSource{Key: "pulumiscale:rules", open: openFake(fs)})
+	loader.AddSource(esc)
+	loader.AddSource(&FileSource{Path: file})
+
+	rules, err := loader.LoadRules(ctx)
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+
+	want := map[string]string{
+		"workers": "urn:pulumi:dev::app::aws:autoscaling/group:Group::file-workers", // file beats output
+		"api":     "urn:pulumi:dev::app::aws:autoscaling/group:Group::config-api",   // config beats output
+		"batch":   "urn:pulumi:dev::app::aws:autoscaling/group:Group::esc-batch",
+	}
+	if len(rules) != len(want) {
+		t.Errorf("got %d rules, want %d", len(rules), len(want))
+	}
+	for pool, urn := range want {
+		if rules[pool].TargetURN != urn {
+			t.Errorf("%s targetUrn = %s, want %s", pool, rules[pool].TargetURN, urn)
+		}
+		if rules[pool].PoolName != pool {
+			t.Errorf("%s PoolName = %s", pool, rules[pool].PoolName)
+		}
+	}
+	// The winning definition replaces the whole rule.
+	if rules["workers"].Min != 1 || rules["workers"].Max != 20 {
+		t.Errorf("workers min/max = %v/%v, want 1/20", rules["workers"].Min, rules["workers"].Max)
+	}
+}
+
+func TestConfigLoaderErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no rules anywhere", func(t *testing.T) {
+		loader := &ConfigLoader{Sources: []RuleSource{&OutputSource{Key: "pulumiscale", open: openFake(newFakeStack(nil))}}}
+		if _, err := loader.LoadRules(ctx); err == nil || !strings.Contains(err.Error(), "no scaling rules found") {
+			t.Errorf("expected no rules error, got %v", err)
+		}
+	})
+
+	t.Run("failing source fails the load", func(t *testing.T) {
+		loader := &ConfigLoader{Sources: []RuleSource{&FileSource{Path: filepath.Join(t.TempDir(), "missing.json")}}}
+		if _, err := loader.LoadRules(ctx); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("invalid rule names its source", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(file, []byte(`{"workers": {"configKey": "count"}}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		loader := &ConfigLoader{Sources: []RuleSource{&FileSource{Path: file}}}
+		_, err := loader.LoadRules(ctx)
+		if err == nil || !strings.Contains(err.Error(), "file:"+file) {
+			t.Errorf("expected error naming the file source, got %v", err)
+		}
+	})
+}
@@ -2,22 +2,80 @@ package autoscaler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 )
 
+// stack is the subset of auto.Stack used by the StateManager.
+// It exists so tests can substitute an in-memory fake for the Automation API.
+type stack interface {
+	GetConfigWithOptions(ctx context.Context, key string, opts *auto.ConfigOptions) (auto.ConfigValue, error)
+	SetConfigWithOptions(ctx context.Context, key string, val auto.ConfigValue, opts *auto.ConfigOptions) error
+	RemoveConfigWithOptions(ctx context.Context, key string, opts *auto.ConfigOptions) error
+	Up(ctx context.Context, opts ...optup.Option) (auto.UpResult, error)
+	Preview(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error)
+	Info(ctx context.Context) (auto.StackSummary, error)
+	Outputs(ctx context.Context) (auto.OutputMap, error)
+	GetAllConfig(ctx context.Context) (auto.ConfigMap, error)
+	Export(ctx context.Context) (apitype.UntypedDeployment, error)
+	History(ctx context.Context, pageSize int, page int, opts ...opthistory.Option) ([]auto.UpdateSummary, error)
+	Refresh(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error)
+}
+
+// localStack returns an opener for a stack whose program lives in workDir.
+// We use UpsertStack to get a handle to the stack, assuming it already exists.
+func localStack(stackName, workDir string) func(ctx context.Context) (stack, error) {
+	return func(ctx context.Context) (stack, error) {
+		s, err := auto.UpsertStackLocalSource(ctx, stackName, workDir)
+		if err != nil {
+			return nil, err
+		}
+		return &s, nil
+	}
+}
+
+// defaultFailureRetries is the number of extra up attempts for FailureRetry when the rule doesn't set one.
+const defaultFailureRetries = 3
+
+// failureRetries returns the number of extra up attempts for FailureRetry. Zero means the
+// default and a negative value disables the extra attempts.
+func (r ScalingRule) failureRetries() int {
+	if r.FailureRetries == 0 {
+		return defaultFailureRetries
+	}
+	return max(r.FailureRetries, 0)
+}
+
 // StateManager handles Automation API interactions.
 type StateManager struct {
 	StackName string
 	WorkDir   string
+
+	// Clock drives retry backoff. Nil means the wall clock.
+	Clock Clock
+
+	// open returns a handle to the stack. Nil means a local source stack in WorkDir.
+	open func(ctx context.Context) (stack, error)
+
+	// rand is the jitter source for retry backoff. Nil means math/rand.
+	rand func() float64
 }
 
 func NewStateManager(stackName, workDir string) *StateManager {
@@ -27,114 +85,615 @@ func NewStateManager(stackName, workDir string) *StateManager {
 	}
 }
 
-// GetCurrentCount retrieves the current value of a config key.
-// Returns 0 if key not found (or error).
-func (sm *StateManager) GetCurrentCount(ctx context.Context, key string) (int, error) {
-	s, err := auto.UpsertStackLocalSource(ctx, sm.StackName, sm.WorkDir)
+func (sm *StateManager) clock() Clock {
+	if sm.Clock != nil {
+		return sm.Clock
+	}
+	return realClock{}
+}
+
+func (sm *StateManager) random() float64 {
+	if sm.rand != nil {
+		return sm.rand()
+	}
+	return defaultRand()
+}
+
+// stack opens the stack managed by this StateManager.
+func (sm *StateManager) stack(ctx context.Context) (stack, error) {
+	ctx, span := tracer.Start(ctx, "pulumi.open_stack", trace.WithAttributes(attrStack.String(sm.StackName)))
+	open := sm.open
+	if open == nil {
+		open = localStack(sm.StackName, sm.WorkDir)
+	}
+	s, err := open(ctx)
+	endSpan(span, err)
+	return s, err
+}
+
+// Ping checks that the stack can be opened and its backend answers.
+func (sm *StateManager) Ping(ctx context.Context) error {
+	s, err := sm.stack(ctx)
 	if err != nil {
-		return 0, err
+		return err
 	}
+	_, err = s.Info(ctx)
+	return err
+}
 
-	cfg, err := s.GetConfig(ctx, key)
+// Resources returns the resources in the stack's current state.
+func (sm *StateManager) Resources(ctx context.Context) ([]apitype.ResourceV3, error) {
+	s, err := sm.stack(ctx)
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.Export(ctx)
 	if err != nil {
-		// If key missing, default to 0? Or error?
-		// auto returns error if key not found? No, it might return empty.
-		// If error, likely stack or connection issue.
-		// Assuming 0 if not set is risky for infra scaling.
-		// But for simple integer keys:
-		return 0, err
+		return nil, fmt.Errorf("failed to export stack: %w", err)
+	}
+	var deployment apitype.DeploymentV3
+	if err := json.Unmarshal(export.Deployment, &deployment); err != nil {
+		return nil, fmt.Errorf("failed to decode stack deployment: %w", err)
 	}
+	return deployment.Resources, nil
+}
 
-	// Assuming the config value is stored as a string that can be parsed as int.
-	// Pulumi ConfigValue.Value is string.
-	var val int
-	_, err = fmt.Sscanf(cfg.Value, "%d", &val)
+// Up runs a full, untargeted update of the stack, retrying on concurrent update conflicts.
+func (sm *StateManager) Up(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+	s, err := sm.stack(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse config value '%s' as int: %w", cfg.Value, err)
+		return auto.UpResult{}, err
 	}
+	var res auto.UpResult
+	err = sm.retryOnConcurrency(ctx, RetryPolicy{}, func() error {
+		ctx, span := tracer.Start(ctx, "pulumi.up")
+		var upErr error
+		res, upErr = s.Up(ctx, opts...)
+		endSpan(span, upErr)
+		return upErr
+	})
+	return res, err
+}
 
-	return val, nil
+// History returns the stack's most recent updates, newest first.
+func (sm *StateManager) History(ctx context.Context, limit int) ([]auto.UpdateSummary, error) {
+	s, err := sm.stack(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.History(ctx, limit, 1)
+}
+
+// GetCurrentValue retrieves the current value of the pool, parsed as the rule's type.
+// It comes from the rule's CurrentFrom source when set, and from its config key otherwise.
+func (sm *StateManager) GetCurrentValue(ctx context.Context, rule ScalingRule) (v Value, err error) {
+	ctx, span := tracer.Start(ctx, "autoscaler.current_value", trace.WithAttributes(attrPool.String(rule.PoolName)))
+	defer func() { endSpan(span, err) }()
+
+	if cf := rule.CurrentFrom; cf != nil {
+		if cf.Output != "" {
+			return sm.outputValue(ctx, rule, cf.Output)
+		}
+		urn := cf.Resource
+		if urn == "" {
+			urn = rule.targets()[0]
+		}
+		return sm.resourceValue(ctx, rule, urn, cf.Property)
+	}
+	return sm.ConfigValue(ctx, rule)
+}
+
+// ConfigValue retrieves the value of the rule's config key, parsed as the rule's type.
+func (sm *StateManager) ConfigValue(ctx context.Context, rule ScalingRule) (Value, error) {
+	s, err := sm.stack(ctx)
+	if err != nil {
+		return Value{}, err
+	}
+	v, _, err := getValue(ctx, s, rule)
+	return v, err
+}
+
+// getValue reads and parses the rule's config key, also returning the raw config
+// so callers can restore it verbatim.
+func getValue(ctx context.Context, s stack, rule ScalingRule) (Value, auto.ConfigValue, error) {
+	cfg, err := s.GetConfigWithOptions(ctx, rule.ConfigKey, rule.configOptions())
+	if err != nil {
+		// A missing key is an error too: assuming 0 is risky for infra scaling.
+		return Value{}, cfg, err
+	}
+
+	val, err := parseValue(rule.valueType(), cfg.Value)
+	if err != nil {
+		return Value{}, cfg, err
+	}
+	val.Secret = cfg.Secret || rule.Secret
+	return val, cfg, nil
 }
 
 // Apply updates the config and runs a targeted up.
-func (sm *StateManager) Apply(ctx context.Context, rule ScalingRule, newValue int) error {
-	s, err := auto.UpsertStackLocalSource(ctx, sm.StackName, sm.WorkDir)
+// If the up fails, the rule's FailurePolicy decides whether the config is rolled back,
+// left in place (the pool is then drifted), or the up is retried first.
+// The returned JobResult records the decision even when an error is returned.
+func (sm *StateManager) Apply(ctx context.Context, rule ScalingRule, newValue Value) (JobResult, error) {
+	clock := sm.clock()
+	result := JobResult{
+		Pool:      rule.PoolName,
+		Target:    newValue,
+		Status:    JobFailed,
+		StartedAt: clock.Now(),
+	}
+	finish := func(err error) (JobResult, error) {
+		result.FinishedAt = clock.Now()
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorKind = classifyError(err)
+		} else {
+			result.Status = JobSucceeded
+		}
+		return result, err
+	}
+
+	s, err := sm.stack(ctx)
 	if err != nil {
-		return err
+		return finish(err)
 	}
+	result.Commit = stackCommit(s)
 
-	// 1. Set Config
-	// We set it as a string.
-	err = s.SetConfig(ctx, rule.ConfigKey, auto.ConfigValue{Value: fmt.Sprintf("%d", newValue)})
+	// 0. Remember the previous values so a failed up can be rolled back.
+	plan, err := planWrites(ctx, s, rule, newValue)
 	if err != nil {
-		return fmt.Errorf("failed to set config: %w", err)
+		return finish(err)
+	}
+	writes, prevValue := plan.writes, plan.previous
+	if writes[0].prevErr == nil {
+		result.Previous = prevValue
+	}
+	newValue = plan.target
+	result.Target = newValue
+	result.Derived = plan.derived
+
+	// 1. Set Config
+	// All keys are written before the up so they land in a single update.
+	if restored, err := writeConfig(ctx, s, writes); err != nil {
+		if !restored {
+			result.Decision = DecisionRollbackFailed
+		}
+		return finish(err)
 	}
 
 	// 2. Run Up with Retry
-	return sm.retryOnConcurrency(ctx, func() error {
-		// Targeted Update
-		_, err := s.Up(ctx, optup.Target([]string{rule.TargetURN}))
+	upOpts := []optup.Option{
+		optup.Target(rule.allTargets()),
+		optup.Message(scaleMessage(rule.PoolName, result.Previous, newValue, sourceFrom(ctx))),
+	}
+	if rule.TargetDependents {
+		upOpts = append(upOpts, optup.TargetDependents())
+	}
+	up := func() error {
+		result.Attempts++
+		calls := 0
+		err := sm.retryOnConcurrency(ctx, rule.Retry, func() error {
+			calls++
+			ctx, span := tracer.Start(ctx, "pulumi.up", trace.WithAttributes(
+				attrPool.String(rule.PoolName),
+				attrAttempt.Int(result.Attempts),
+				attrRetry.Int(calls-1),
+			))
+			// Targeted Update
+			res, err := s.Up(ctx, upOpts...)
+			endSpan(span, err)
+			if err == nil && res.Summary.Version > 0 {
+				result.UpdateVersion = res.Summary.Version
+			}
+			return err
+		})
+		result.ConflictRetries += calls - 1
 		return err
-	})
+	}
+	upErr := up()
+	if upErr == nil {
+		return finish(nil)
+	}
+
+	// 3. Handle failure according to policy
+	policy := rule.OnFailure
+	if policy == "" {
+		policy = FailureRollback
+	}
+
+	if policy == FailureRetry {
+		for i := 0; i < rule.failureRetries() && !classifyError(upErr).Permanent(); i++ {
+			delay := rule.Retry.delay(i, sm.random)
+			logFrom(ctx).Warn().
+				Err(upErr).
+				Str("kind", string(classifyError(upErr))).
+				Dur("delay", delay).
+				Msg("Up failed. Retrying...")
+			_, span := tracer.Start(ctx, "autoscaler.retry_backoff", trace.WithAttributes(
+				attrDelay.String(delay.String()),
+				attrKind.String(string(classifyError(upErr))),
+			))
+			select {
+			case <-ctx.Done():
+				upErr = ctx.Err()
+				endSpan(span, upErr)
+			case <-clock.After(delay):
+				span.End()
+				upErr = up()
+			}
+			if upErr == nil {
+				result.Decision = DecisionRetried
+				return finish(nil)
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		policy = FailureRollback
+	}
+
+	if policy == FailureLeave {
+		result.Decision = DecisionDrifted
+		return finish(fmt.Errorf("up failed, config left at new value: %w", upErr))
+	}
+
+	if err := restoreConfig(ctx, s, writes); err != nil {
+		if errors.Is(err, errPreviousUnknown) {
+			result.Decision = DecisionDrifted
+		} else {
+			result.Decision = DecisionRollbackFailed
+		}
+		return finish(fmt.Errorf("up failed (%v) and config rollback failed: %w", upErr, err))
+	}
+	logFrom(ctx).Info().Stringer("value", prevValue).Msg("Rolled back config after failed up")
+	result.Decision = DecisionRolledBack
+	return finish(fmt.Errorf("up failed, config rolled back: %w", upErr))
+}
+
+// writePlan is the set of config writes that move a rule's pool to a new value.
+type writePlan struct {
+	writes   []configWrite // the rule's ConfigKey first, then its derived keys
+	previous Value         // typed previous value of ConfigKey, if writes[0].prevErr is nil
+	target   Value         // the new value with its secret flag resolved
+	derived  map[string]Value
 }
 
-// retryOnConcurrency implements exponential backoff for 409 Conflict / Concurrent Update errors.
-func (sm *StateManager) retryOnConcurrency(ctx context.Context, op func() error) error {
-	maxRetries := 5
-	baseDelay := 1 * time.Second
+// planWrites reads the previous values of rule's keys, so they can be restored, and
+// computes the writes that set them for newValue. Values are compared as typed values,
+// but restored from the raw config.
+func planWrites(ctx context.Context, s stack, rule ScalingRule, newValue Value) (writePlan, error) {
+	_, span := tracer.Start(ctx, "pulumi.get_config", trace.WithAttributes(attrConfig.String(rule.ConfigKey)))
+	prevValue, previous, prevErr := getValue(ctx, s, rule)
+	endSpan(span, prevErr)
+	newValue.Secret = newValue.Secret || rule.Secret || previous.Secret
 
-	for i := 0; i <= maxRetries; i++ {
-		err := op()
+	plan := writePlan{previous: prevValue, target: newValue}
+	plan.writes = []configWrite{{
+		key:      rule.ConfigKey,
+		opts:     rule.configOptions(),
+		value:    auto.ConfigValue{Value: newValue.Raw(), Secret: newValue.Secret},
+		previous: previous,
+		prevErr:  prevErr,
+	}}
+	derived, err := rule.deriveValues(newValue, prevValue)
+	if err != nil {
+		return writePlan{}, err
+	}
+	plan.derived = derived
+	for _, dk := range rule.DerivedKeys {
+		opts := &auto.ConfigOptions{Path: dk.Path}
+		prev, err := s.GetConfigWithOptions(ctx, dk.ConfigKey, opts)
+		// A derived key may be new; rolling it back means removing it again.
+		missing := false
+		if err != nil {
+			if missing, err = configMissing(ctx, s, dk.ConfigKey, dk.Path, err); missing {
+				err = nil
+			}
+		}
+		v := derived[dk.ConfigKey]
+		v.Secret = v.Secret || prev.Secret
+		plan.writes = append(plan.writes, configWrite{
+			key:      dk.ConfigKey,
+			opts:     opts,
+			value:    auto.ConfigValue{Value: v.Raw(), Secret: v.Secret},
+			previous: prev,
+			missing:  missing,
+			prevErr:  err,
+		})
+	}
+	return plan, nil
+}
+
+// writeConfig sets every key in writes. If a write fails, the keys written before it are
+// restored; restored is false if that rollback failed too.
+// Config values are always strings; the type only affects parsing and formatting.
+func writeConfig(ctx context.Context, s stack, writes []configWrite) (restored bool, err error) {
+	for i, w := range writes {
+		_, span := tracer.Start(ctx, "pulumi.set_config", trace.WithAttributes(attrConfig.String(w.key)))
+		err := s.SetConfigWithOptions(ctx, w.key, w.value, w.opts)
+		endSpan(span, err)
+		if err != nil {
+			if restoreErr := restoreConfig(ctx, s, writes[:i]); restoreErr != nil {
+				return false, fmt.Errorf("failed to set config %s (%v) and rollback failed: %w", w.key, err, restoreErr)
+			}
+			return true, fmt.Errorf("failed to set config %s: %w", w.key, err)
+		}
+	}
+	return true, nil
+}
+
+// configWrite is one config key written by Apply and the value to restore on rollback.
+type configWrite struct {
+	key      string
+	opts     *auto.ConfigOptions
+	value    auto.ConfigValue
+	previous auto.ConfigValue
+	missing  bool // the key didn't exist before the write
+	prevErr  error
+}
+
+// configMissing reports whether key is absent from the stack config after reading it
+// failed with getErr. The CLI doesn't return a typed not-found error, so the key is
+// looked up in GetAllConfig instead; getErr is returned if the key turns out to exist.
+func configMissing(ctx context.Context, s stack, key string, path bool, getErr error) (bool, error) {
+	cfg, err := s.GetAllConfig(ctx)
+	if err != nil {
+		return false, errors.Join(getErr, err)
+	}
+	if _, ok := lookupConfig(cfg, key); ok {
+		return false, getErr
+	}
+	if !path {
+		return true, nil
+	}
+	// A path key like "nodeGroup.minSize" lives inside the JSON value of "nodeGroup".
+	top, rest, _ := strings.Cut(key, ".")
+	v, ok := lookupConfig(cfg, top)
+	if !ok {
+		return true, nil
+	}
+	var obj any
+	if json.Unmarshal([]byte(v.Value), &obj) != nil {
+		return false, getErr
+	}
+	for _, part := range strings.Split(rest, ".") {
+		m, isMap := obj.(map[string]any)
+		if !isMap {
+			return false, getErr
+		}
+		if obj, ok = m[part]; !ok {
+			return true, nil
+		}
+	}
+	return false, getErr
+}
+
+// lookupConfig finds key in cfg, whose keys are namespaced ("project:key") unless key already is.
+func lookupConfig(cfg auto.ConfigMap, key string) (auto.ConfigValue, bool) {
+	if v, ok := cfg[key]; ok {
+		return v, true
+	}
+	if strings.Contains(key, ":") {
+		return auto.ConfigValue{}, false
+	}
+	for k, v := range cfg {
+		if strings.HasSuffix(k, ":"+key) {
+			return v, true
+		}
+	}
+	return auto.ConfigValue{}, false
+}
+
+// errPreviousUnknown means a write can't be rolled back because its old value couldn't be read.
+var errPreviousUnknown = errors.New("previous config value is unknown")
+
+// restoreConfig writes back the previous values of writes and removes keys that didn't exist.
+// Keys whose previous value couldn't be read are left alone; restoring
+// an empty value would be worse than leaving the new one.
+func restoreConfig(ctx context.Context, s stack, writes []configWrite) (err error) {
+	// Use a fresh context so the rollback still happens if the up was cancelled.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "pulumi.rollback_config")
+	defer func() { endSpan(span, err) }()
+
+	var errs []error
+	for _, w := range writes {
+		if w.prevErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w (%v)", w.key, errPreviousUnknown, w.prevErr))
+			continue
+		}
+		if w.missing {
+			if err := s.RemoveConfigWithOptions(ctx, w.key, w.opts); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", w.key, err))
+			}
+			continue
+		}
+		if err := s.SetConfigWithOptions(ctx, w.key, w.previous, w.opts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryOnConcurrency retries op with exponential backoff while it fails with a concurrent update error.
+// Errors are classified with the Automation API's helpers and returned as a *StackError,
+// so anything that isn't a lock conflict (e.g. a provider 409) fails immediately.
+func (sm *StateManager) retryOnConcurrency(ctx context.Context, policy RetryPolicy, op func() error) error {
+	maxRetries := policy.maxRetries()
+	clock := sm.clock()
+
+	for i := 0; ; i++ {
+		err := wrapStackError(op())
 		if err == nil {
 			return nil
 		}
 
-		// Check for concurrent update error
-		// The error message usually contains "conflict" or "concurrent update".
-		// Since we wrap the standard auto API, we rely on string matching.
-		errMsg := strings.ToLower(err.Error())
-		isConflict := strings.Contains(errMsg, "conflict") || strings.Contains(errMsg, "concurrent update")
-
-		if !isConflict {
-			return err // Non-retryable error
+		kind := classifyError(err)
+		if !kind.Retryable() {
+			return err
 		}
 
 		if i == maxRetries {
-			return fmt.Errorf("max retries exceeded for concurrent update: %w", err)
+			return &StackError{Kind: kind, Err: fmt.Errorf("max retries exceeded for concurrent update: %w", err)}
 		}
 
-		// Exponential Backoff
-		delay := baseDelay * time.Duration(math.Pow(2, float64(i)))
-		log.Info().Dur("delay", delay).Msg("Concurrent update detected. Retrying...")
-		
+		delay := policy.delay(i, sm.random)
+		logFrom(ctx).Info().Dur("delay", delay).Int("attempt", i+1).Msg("Concurrent update detected. Retrying...")
+
+		_, span := tracer.Start(ctx, "autoscaler.retry_backoff", trace.WithAttributes(
+			attrDelay.String(delay.String()),
+			attrKind.String(string(kind)),
+		))
 		select {
 		case <-ctx.Done():
+			endSpan(span, ctx.Err())
 			return ctx.Err()
-		case <-time.After(delay):
-			continue
+		case <-clock.After(delay):
+			span.End()
 		}
 	}
-	return nil
 }
 
-// Preview runs a preview update (dry run).
-func (sm *StateManager) Preview(ctx context.Context, rule ScalingRule, newValue int) (string, error) {
-	s, err := auto.UpsertStackLocalSource(ctx, sm.StackName, sm.WorkDir)
+// PreviewResult summarises a dry run.
+type PreviewResult struct {
+	// Raw preview output from the Pulumi CLI.
+	StdOut string
+
+	// Resources outside the rule's declared targets that the update would change,
+	// e.g. dependents pulled in by TargetDependents.
+	Extra []string
+}
+
+// Preview runs a preview update (dry run). The new config is staged the way Apply writes it,
+// but in a temporary copy of the stack's config file, so the stack's own config never changes.
+func (sm *StateManager) Preview(ctx context.Context, rule ScalingRule, newValue Value) (PreviewResult, error) {
+	s, err := sm.stack(ctx)
 	if err != nil {
-		return "", err
+		return PreviewResult{}, err
 	}
 
-	res, err := s.Preview(ctx, 
-		optpreview.Target([]string{rule.TargetURN}),
-		// optpreview.Config is not available or I'm using it wrong.
-		// For now, we preview without explicit ephemeral config change.
-		// Real DryRun might need to actually SetConfig then Preview then Revert?
-	)
+	plan, err := planWrites(ctx, s, rule, newValue)
 	if err != nil {
-		return "", err
+		return PreviewResult{}, err
 	}
-	
+	// The new values go into a copy of the stack's config, so an up running meanwhile
+	// never sees them and there is nothing to restore afterwards.
+	configFile, cleanup, err := stageConfig(ctx, s, plan.writes)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	defer cleanup()
+
+	// Collect the resources the engine plans to touch.
+	declared := rule.allTargets()
+	var extra []string
+	events := make(chan events.EngineEvent)
+	collectCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-collectCtx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if urn, touched := touchedURN(ev); touched && !slices.Contains(declared, urn) && !slices.Contains(extra, urn) {
+					extra = append(extra, urn)
+				}
+			}
+		}
+	}()
+
+	opts := []optpreview.Option{
+		optpreview.Target(rule.allTargets()),
+		optpreview.EventStreams(events),
+		optpreview.ConfigFile(configFile),
+	}
+	if rule.TargetDependents {
+		opts = append(opts, optpreview.TargetDependents())
+	}
+	previewCtx, span := tracer.Start(ctx, "pulumi.preview", trace.WithAttributes(attrPool.String(rule.PoolName)))
+	res, err := s.Preview(previewCtx, opts...)
+	endSpan(span, err)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	// The event stream is closed once the preview has flushed all events.
+	<-done
+
 	// Return the diff or summary
 	// Automation API Stdout is captured? res.StdOut
-	return res.StdOut, nil
+	return PreviewResult{StdOut: res.StdOut, Extra: extra}, nil
+}
+
+// stageConfig copies the stack's config file to a temporary file and writes the planned
+// values there. The caller previews with the copy and removes it with cleanup.
+func stageConfig(ctx context.Context, s stack, writes []configWrite) (path string, cleanup func(), err error) {
+	src, err := stackConfigFile(s)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", nil, fmt.Errorf("failed to read stack config: %w", err)
+	}
+	f, err := os.CreateTemp("", "pulumiscale-preview-*.yaml")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.Remove(f.Name()) }
+	_, err = f.Write(data)
+	if err = errors.Join(err, f.Close()); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy stack config: %w", err)
+	}
+
+	for _, w := range writes {
+		opts := &auto.ConfigOptions{ConfigFile: f.Name()}
+		if w.opts != nil {
+			opts.Path = w.opts.Path
+		}
+		_, span := tracer.Start(ctx, "pulumi.set_config", trace.WithAttributes(attrConfig.String(w.key)))
+		err := s.SetConfigWithOptions(ctx, w.key, w.value, opts)
+		endSpan(span, err)
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to stage config %s: %w", w.key, err)
+		}
+	}
+	return f.Name(), cleanup, nil
+}
+
+// stackConfigFile returns the path of the stack's Pulumi.<stack>.yaml.
+func stackConfigFile(s stack) (string, error) {
+	if g, ok := s.(*gitStack); ok {
+		s = g.stack
+	}
+	ws, ok := s.(interface {
+		Name() string
+		Workspace() auto.Workspace
+	})
+	if !ok {
+		return "", errors.New("stack has no local workspace to preview in")
+	}
+	name := ws.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	return filepath.Join(ws.Workspace().WorkDir(), "Pulumi."+name+".yaml"), nil
+}
+
+// touchedURN returns the URN of a resource step event that changes something.
+func touchedURN(ev events.EngineEvent) (string, bool) {
+	if ev.ResourcePreEvent == nil {
+		return "", false
+	}
+	md := ev.ResourcePreEvent.Metadata
+	if md.Op == apitype.OpSame || md.Type == "pulumi:pulumi:Stack" {
+		return "", false
+	}
+	return md.URN, true
 }
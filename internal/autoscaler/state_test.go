@@ -2,25 +2,58 @@ package autoscaler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 )
 
-// Export retryOnConcurrency for testing (or keep it private and test via a public wrapper or reflection, but internal tests can see private methods)
-// Since this is `package autoscaler`, we can test private methods of `StateManager`.
+// fakeClock fires every After immediately and records the requested delays,
+// so backoff can be asserted without sleeping.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func conflictError() error {
+	return &StackError{Kind: ErrorConcurrentUpdate, Err: errors.New("[409] Conflict: Another update is currently in progress.")}
+}
 
 func TestRetryOnConcurrency(t *testing.T) {
-	sm := &StateManager{}
 	ctx := context.Background()
 
 	t.Run("success on first try", func(t *testing.T) {
+		sm := &StateManager{Clock: &fakeClock{}}
 		calls := 0
 		op := func() error {
 			calls++
 			return nil
 		}
-		err := sm.retryOnConcurrency(ctx, op)
+		err := sm.retryOnConcurrency(ctx, RetryPolicy{}, op)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -29,69 +62,773 @@ func TestRetryOnConcurrency(t *testing.T) {
 		}
 	})
 
+	t.Run("retries disabled", func(t *testing.T) {
+		sm := &StateManager{Clock: &fakeClock{}}
+		calls := 0
+		op := func() error {
+			calls++
+			return conflictError()
+		}
+		if err := sm.retryOnConcurrency(ctx, RetryPolicy{MaxRetries: -1}, op); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 call (retries disabled), got %d", calls)
+		}
+	})
+
 	t.Run("fail with non-conflict error", func(t *testing.T) {
+		sm := &StateManager{Clock: &fakeClock{}}
 		calls := 0
 		op := func() error {
 			calls++
 			return errors.New("network error")
 		}
-		err := sm.retryOnConcurrency(ctx, op)
+		err := sm.retryOnConcurrency(ctx, RetryPolicy{}, op)
 		if err == nil || err.Error() != "network error" {
 			t.Errorf("Expected network error, got %v", err)
 		}
 		if calls != 1 {
 			t.Errorf("Expected 1 call (no retry), got %d", calls)
 		}
+		if kind := classifyError(err); kind != ErrorUnknown {
+			t.Errorf("Expected kind %q, got %q", ErrorUnknown, kind)
+		}
+	})
+
+	t.Run("provider conflict is not retried", func(t *testing.T) {
+		sm := &StateManager{Clock: &fakeClock{}}
+		calls := 0
+		op := func() error {
+			calls++
+			return errors.New("error: 409 Conflict: the resource is being modified")
+		}
+		if err := sm.retryOnConcurrency(ctx, RetryPolicy{}, op); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 call (no retry), got %d", calls)
+		}
 	})
 
 	t.Run("retry on conflict", func(t *testing.T) {
+		clock := &fakeClock{}
+		sm := &StateManager{Clock: clock}
 		calls := 0
 		op := func() error {
 			calls++
 			if calls < 3 {
-				return errors.New("error: conflict: another update is in progress")
+				return conflictError()
 			}
 			return nil
 		}
-		
-		// Use a short context or trust logic speed?
-		// Retry logic has exponential backoff 1s, 2s...
-		// This test will take ~3 seconds. Acceptable.
-		
-		start := time.Now()
-		err := sm.retryOnConcurrency(ctx, op)
-		duration := time.Since(start)
 
+		err := sm.retryOnConcurrency(ctx, RetryPolicy{}, op)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
 		if calls != 3 {
 			t.Errorf("Expected 3 calls, got %d", calls)
 		}
-		// Expect delay: 0 (fail) -> 1s -> 1 (fail) -> 2s -> 2 (success). Total wait ~3s.
-		if duration < 2*time.Second {
-			t.Errorf("Expected backoff delay, but finished too fast: %v", duration)
+		// Expect delay: 0 (fail) -> 1s -> 1 (fail) -> 2s -> 2 (success).
+		want := []time.Duration{time.Second, 2 * time.Second}
+		if fmt.Sprint(clock.sleeps) != fmt.Sprint(want) {
+			t.Errorf("Expected backoff %v, got %v", want, clock.sleeps)
 		}
 	})
 
 	t.Run("max retries exceeded", func(t *testing.T) {
+		clock := &fakeClock{}
+		sm := &StateManager{Clock: clock}
 		calls := 0
 		op := func() error {
 			calls++
-			return errors.New("concurrent update")
+			return conflictError()
 		}
 
-		// We don't want to wait 60s for this test.
-		// We can't easily mock the backoff constants in the current impl without refactoring.
-		// For this test, I will skip execution or accept it takes time, OR verify just 1 retry if I can cancel ctx?
-		
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-		defer cancel()
-		
-		err := sm.retryOnConcurrency(ctx, op)
+		policy := RetryPolicy{MaxRetries: 3, BaseDelaySeconds: 10, MaxDelaySeconds: 25}
+		err := sm.retryOnConcurrency(ctx, policy, op)
 		if err == nil {
-			t.Error("Expected error, got nil")
+			t.Fatal("Expected error, got nil")
+		}
+		if calls != 4 {
+			t.Errorf("Expected 4 calls, got %d", calls)
+		}
+		if kind := classifyError(err); kind != ErrorConcurrentUpdate {
+			t.Errorf("Expected kind %q, got %q", ErrorConcurrentUpdate, kind)
+		}
+		want := []time.Duration{10 * time.Second, 20 * time.Second, 25 * time.Second}
+		if fmt.Sprint(clock.sleeps) != fmt.Sprint(want) {
+			t.Errorf("Expected backoff %v, got %v", want, clock.sleeps)
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		sm := &StateManager{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := sm.retryOnConcurrency(ctx, RetryPolicy{}, conflictError)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		rnd     float64
+		want    time.Duration
+	}{
+		{name: "low jitter", policy: RetryPolicy{BaseDelaySeconds: 10, Jitter: 0.5}, rnd: 0, want: 5 * time.Second},
+		{name: "mid jitter", policy: RetryPolicy{BaseDelaySeconds: 10, Jitter: 0.5}, rnd: 0.5, want: 10 * time.Second},
+		{name: "high jitter", policy: RetryPolicy{BaseDelaySeconds: 10, Jitter: 0.5}, rnd: 0.9, want: 14 * time.Second},
+		{name: "capped delay jitters down", policy: RetryPolicy{MaxDelaySeconds: 60, Jitter: 0.5}, attempt: 10, rnd: 0, want: 30 * time.Second},
+		{name: "capped delay stays under max", policy: RetryPolicy{MaxDelaySeconds: 60, Jitter: 0.5}, attempt: 10, rnd: 0.99, want: 60 * time.Second},
+		{name: "default cap", policy: RetryPolicy{Jitter: 0.5}, attempt: 20, rnd: 0.99, want: defaultMaxDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.delay(tt.attempt, func() float64 { return tt.rnd }); got != tt.want {
+				t.Errorf("delay(%d) with rnd=%v = %v, want %v", tt.attempt, tt.rnd, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeStack is an in-memory stand-in for auto.Stack.
+type fakeStack struct {
+	config  map[string]auto.ConfigValue
+	upErrs  []error // returned by successive Up calls; nil once exhausted
+	upCalls int
+	upOpts  optup.Options // options of the last Up call
+
+	previewEvents []events.EngineEvent // streamed to EventStreams by Preview
+	previewOpts   optpreview.Options
+	previewFn     func(config map[string]auto.ConfigValue) []events.EngineEvent // overrides previewEvents
+	staged        map[string]auto.ConfigValue                                   // written to a ConfigFile other than the stack's
+	stagedFile    string
+	workDir       string // holds Pulumi.dev.yaml, if the test writes one
+
+	outputs   auto.OutputMap
+	resources []apitype.ResourceV3 // returned by Export
+	history   []auto.UpdateSummary
+
+	refreshCalls int
+	refreshOpts  optrefresh.Options
+	onRefresh    func() // called by Refresh, e.g. to change state mid-refresh
+}
+
+func newFakeStack(config map[string]string) *fakeStack {
+	fs := &fakeStack{config: map[string]auto.ConfigValue{}}
+	for k, v := range config {
+		fs.config[k] = auto.ConfigValue{Value: v}
+	}
+	return fs
+}
+
+func (fs *fakeStack) GetConfigWithOptions(_ context.Context, key string, _ *auto.ConfigOptions) (auto.ConfigValue, error) {
+	v, ok := fs.config[key]
+	if !ok {
+		return auto.ConfigValue{}, fmt.Errorf("exit status 255: reading %s", key)
+	}
+	return v, nil
+}
+
+func (fs *fakeStack) SetConfigWithOptions(_ context.Context, key string, val auto.ConfigValue, opts *auto.ConfigOptions) error {
+	if opts != nil && opts.ConfigFile != "" {
+		if fs.staged == nil {
+			fs.staged = map[string]auto.ConfigValue{}
+		}
+		fs.staged[key] = val
+		fs.stagedFile = opts.ConfigFile
+		return nil
+	}
+	fs.config[key] = val
+	return nil
+}
+
+func (fs *fakeStack) RemoveConfigWithOptions(_ context.Context, key string, _ *auto.ConfigOptions) error {
+	delete(fs.config, key)
+	return nil
+}
+
+func (fs *fakeStack) Up(_ context.Context, opts ...optup.Option) (auto.UpResult, error) {
+	fs.upCalls++
+	fs.upOpts = optup.Options{}
+	for _, o := range opts {
+		o.ApplyOption(&fs.upOpts)
+	}
+	if len(fs.upErrs) > 0 {
+		err := fs.upErrs[0]
+		fs.upErrs = fs.upErrs[1:]
+		if err != nil {
+			return auto.UpResult{}, err
+		}
+	}
+	return auto.UpResult{Summary: auto.UpdateSummary{Version: fs.upCalls}}, nil
+}
+
+func (fs *fakeStack) Preview(_ context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+	fs.previewOpts = optpreview.Options{}
+	for _, o := range opts {
+		o.ApplyOption(&fs.previewOpts)
+	}
+	evs := fs.previewEvents
+	if fs.previewFn != nil {
+		config := maps.Clone(fs.config)
+		if fs.previewOpts.ConfigFile != "" && fs.previewOpts.ConfigFile == fs.stagedFile {
+			maps.Copy(config, fs.staged)
+		}
+		evs = fs.previewFn(config)
+	}
+	for _, ch := range fs.previewOpts.EventStreams {
+		for _, ev := range evs {
+			ch <- ev
+		}
+		close(ch)
+	}
+	return auto.PreviewResult{StdOut: "preview"}, nil
+}
+
+func (fs *fakeStack) Info(context.Context) (auto.StackSummary, error) {
+	return auto.StackSummary{Name: "dev", Current: true}, nil
+}
+
+func (fs *fakeStack) Name() string { return "acme/dev" }
+
+func (fs *fakeStack) Workspace() auto.Workspace { return fakeWorkspace{dir: fs.workDir} }
+
+// fakeWorkspace only knows its directory; Preview reads the stack config file from it.
+type fakeWorkspace struct {
+	auto.Workspace
+	dir string
+}
+
+func (w fakeWorkspace) WorkDir() string { return w.dir }
+
+func (fs *fakeStack) Outputs(context.Context) (auto.OutputMap, error) {
+	return fs.outputs, nil
+}
+
+func (fs *fakeStack) GetAllConfig(context.Context) (auto.ConfigMap, error) {
+	return auto.ConfigMap(fs.config), nil
+}
+
+func (fs *fakeStack) Export(context.Context) (apitype.UntypedDeployment, error) {
+	data, err := json.Marshal(apitype.DeploymentV3{Resources: fs.resources})
+	return apitype.UntypedDeployment{Version: 3, Deployment: data}, err
+}
+
+func (fs *fakeStack) History(_ context.Context, pageSize int, _ int, _ ...opthistory.Option) ([]auto.UpdateSummary, error) {
+	if pageSize > 0 && len(fs.history) > pageSize {
+		return fs.history[:pageSize], nil
+	}
+	return fs.history, nil
+}
+
+func (fs *fakeStack) Refresh(_ context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+	fs.refreshCalls++
+	fs.refreshOpts = optrefresh.Options{}
+	for _, o := range opts {
+		o.ApplyOption(&fs.refreshOpts)
+	}
+	if fs.onRefresh != nil {
+		fs.onRefresh()
+	}
+	return auto.RefreshResult{}, nil
+}
+
+func stepEvent(op apitype.OpType, urn string) events.EngineEvent {
+	return events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: apitype.StepEventMetadata{Op: op, URN: urn}},
+	}}
+}
+
+func newFakeStateManager(fs *fakeStack) *StateManager {
+	return &StateManager{
+		open: func(context.Context) (stack, error) { return fs, nil },
+	}
+}
+
+func TestApplyFailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	upFailed := errors.New("provider error: InvalidParameterValue")
+
+	tests := []struct {
+		name         string
+		policy       FailurePolicy
+		retries      int
+		upErrs       []error
+		wantErr      bool
+		wantValue    string
+		wantDecision FailureDecision
+		wantAttempts int
+		wantKind     ErrorKind
+	}{
+		{
+			name:         "success",
+			policy:       FailureRollback,
+			wantValue:    "5",
+			wantAttempts: 1,
+		},
+		{
+			name:         "default policy rolls back",
+			upErrs:       []error{upFailed},
+			wantErr:      true,
+			wantValue:    "3",
+			wantDecision: DecisionRolledBack,
+			wantAttempts: 1,
+		},
+		{
+			name:         "leave marks drifted",
+			policy:       FailureLeave,
+			upErrs:       []error{upFailed},
+			wantErr:      true,
+			wantValue:    "5",
+			wantDecision: DecisionDrifted,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retry succeeds",
+			policy:       FailureRetry,
+			upErrs:       []error{upFailed, upFailed},
+			wantValue:    "5",
+			wantDecision: DecisionRetried,
+			wantAttempts: 3,
+		},
+		{
+			name:         "retry exhausted rolls back",
+			policy:       FailureRetry,
+			upErrs:       []error{upFailed, upFailed, upFailed, upFailed},
+			wantErr:      true,
+			wantValue:    "3",
+			wantDecision: DecisionRolledBack,
+			wantAttempts: 4,
+		},
+		{
+			name:         "retry disabled rolls back",
+			policy:       FailureRetry,
+			retries:      -1,
+			upErrs:       []error{upFailed},
+			wantErr:      true,
+			wantValue:    "3",
+			wantDecision: DecisionRolledBack,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retry skips permanent errors",
+			policy:       FailureRetry,
+			upErrs:       []error{&StackError{Kind: ErrorCompilation, Err: errors.New("error: an unhandled error occurred: compilation failed")}},
+			wantErr:      true,
+			wantValue:    "3",
+			wantDecision: DecisionRolledBack,
+			wantAttempts: 1,
+			wantKind:     ErrorCompilation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeStack(map[string]string{"count": "3"})
+			fs.upErrs = tt.upErrs
+			sm := newFakeStateManager(fs)
+			sm.Clock = &fakeClock{}
+			rule := ScalingRule{PoolName: "workers", TargetURN: "urn", ConfigKey: "count", OnFailure: tt.policy, FailureRetries: tt.retries}
+
+			result, err := sm.Apply(ctx, rule, IntValue(5))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := fs.config["count"].Value; got != tt.wantValue {
+				t.Errorf("config value = %s, want %s", got, tt.wantValue)
+			}
+			if result.Decision != tt.wantDecision {
+				t.Errorf("decision = %q, want %q", result.Decision, tt.wantDecision)
+			}
+			if result.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", result.Attempts, tt.wantAttempts)
+			}
+			wantKind := tt.wantKind
+			if wantKind == "" {
+				wantKind = ErrorUnknown
+			}
+			if tt.wantErr && result.ErrorKind != wantKind {
+				t.Errorf("error kind = %q, want %q", result.ErrorKind, wantKind)
+			}
+			if !result.Previous.Equal(IntValue(3)) || !result.Target.Equal(IntValue(5)) {
+				t.Errorf("previous/target = %v/%v, want 3/5", result.Previous, result.Target)
+			}
+		})
+	}
+
+	t.Run("unknown previous value marks drifted", func(t *testing.T) {
+		fs := newFakeStack(nil)
+		fs.upErrs = []error{upFailed}
+		sm := newFakeStateManager(fs)
+		rule := ScalingRule{PoolName: "workers", TargetURN: "urn", ConfigKey: "count"}
+
+		result, err := sm.Apply(ctx, rule, IntValue(5))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if result.Decision != DecisionDrifted {
+			t.Errorf("decision = %q, want %q", result.Decision, DecisionDrifted)
+		}
+	})
+}
+
+func TestApplyTypedValues(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("float path", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"gpu.share": "0.25"})
+		sm := newFakeStateManager(fs)
+		rule := ScalingRule{PoolName: "gpu", TargetURN: "urn", ConfigKey: "gpu.share", Path: true, Type: ValueFloat, Max: 1}
+
+		current, err := sm.GetCurrentValue(ctx, rule)
+		if err != nil {
+			t.Fatalf("GetCurrentValue() error = %v", err)
+		}
+		if !current.Equal(FloatValue(0.25)) {
+			t.Errorf("current = %v, want 0.25", current)
+		}
+		if _, err := sm.Apply(ctx, rule, FloatValue(0.5)); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if got := fs.config["gpu.share"].Value; got != "0.5" {
+			t.Errorf("config value = %s, want 0.5", got)
 		}
-		// Should fail with context deadline exceeded or max retries
 	})
+
+	t.Run("secret string", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"instanceType": "m5.large"})
+		sm := newFakeStateManager(fs)
+		rule := ScalingRule{PoolName: "workers", TargetURN: "urn", ConfigKey: "instanceType", Type: ValueString, Secret: true}
+
+		result, err := sm.Apply(ctx, rule, StringValue("m5.xlarge"))
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		cfg := fs.config["instanceType"]
+		if cfg.Value != "m5.xlarge" || !cfg.Secret {
+			t.Errorf("config = %+v, want secret m5.xlarge", cfg)
+		}
+		if result.Target.String() != "[secret]" {
+			t.Errorf("target should be redacted, got %s", result.Target)
+		}
+	})
+
+	t.Run("unparseable value", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"count": "three"})
+		sm := newFakeStateManager(fs)
+		rule := ScalingRule{PoolName: "workers", TargetURN: "urn", ConfigKey: "count"}
+
+		if _, err := sm.GetCurrentValue(ctx, rule); err == nil {
+			t.Error("expected parse error")
+		}
+	})
+}
+
+func TestApplyDerivedKeys(t *testing.T) {
+	ctx := context.Background()
+	rule := ScalingRule{
+		PoolName:   "workers",
+		TargetURN:  "urn:asg",
+		TargetURNs: []string{"urn:lt", "urn:asg"},
+		ConfigKey:  "desiredSize",
+		DerivedKeys: []DerivedKey{
+			{ConfigKey: "maxSize", Value: "desired + 2"},
+			{ConfigKey: "nodeGroup.minSize", Value: "max(desired - 1, min)", Path: true},
+		},
+		Min: 1,
+		Max: 10,
+	}
+
+	t.Run("writes all keys in one targeted up", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"desiredSize": "3", "maxSize": "5", "nodeGroup.minSize": "2"})
+		sm := newFakeStateManager(fs)
+
+		result, err := sm.Apply(ctx, rule, IntValue(6))
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		want := map[string]string{"desiredSize": "6", "maxSize": "8", "nodeGroup.minSize": "5"}
+		for k, v := range want {
+			if got := fs.config[k].Value; got != v {
+				t.Errorf("config %s = %s, want %s", k, got, v)
+			}
+		}
+		if fs.upCalls != 1 {
+			t.Errorf("up calls = %d, want 1", fs.upCalls)
+		}
+		if got := fmt.Sprint(fs.upOpts.Target); got != "[urn:asg urn:lt]" {
+			t.Errorf("targets = %s, want [urn:asg urn:lt]", got)
+		}
+		if !result.Derived["maxSize"].Equal(IntValue(8)) {
+			t.Errorf("derived maxSize = %v, want 8", result.Derived["maxSize"])
+		}
+	})
+
+	t.Run("rollback restores every key", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"desiredSize": "3", "maxSize": "5", "nodeGroup.minSize": "2"})
+		fs.upErrs = []error{errors.New("boom")}
+		sm := newFakeStateManager(fs)
+
+		result, err := sm.Apply(ctx, rule, IntValue(6))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if result.Decision != DecisionRolledBack {
+			t.Errorf("decision = %q, want %q", result.Decision, DecisionRolledBack)
+		}
+		want := map[string]string{"desiredSize": "3", "maxSize": "5", "nodeGroup.minSize": "2"}
+		for k, v := range want {
+			if got := fs.config[k].Value; got != v {
+				t.Errorf("config %s = %s, want %s", k, got, v)
+			}
+		}
+	})
+
+	t.Run("rollback removes new keys", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"desiredSize": "3", "maxSize": "5"})
+		fs.upErrs = []error{errors.New("boom")}
+		sm := newFakeStateManager(fs)
+
+		result, err := sm.Apply(ctx, rule, IntValue(6))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if result.Decision != DecisionRolledBack {
+			t.Errorf("decision = %q, want %q", result.Decision, DecisionRolledBack)
+		}
+		if v, ok := fs.config["nodeGroup.minSize"]; ok {
+			t.Errorf("nodeGroup.minSize = %s, want it removed", v.Value)
+		}
+		if got := fs.config["maxSize"].Value; got != "5" {
+			t.Errorf("config maxSize = %s, want 5", got)
+		}
+	})
+}
+
+func TestTargetDependents(t *testing.T) {
+	ctx := context.Background()
+	rule := ScalingRule{
+		PoolName:         "workers",
+		TargetURN:        "urn:asg",
+		AlsoTarget:       []string{"urn:alarm"},
+		TargetDependents: true,
+		ConfigKey:        "count",
+		Max:              10,
+	}
+
+	t.Run("up", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"count": "3"})
+		sm := newFakeStateManager(fs)
+
+		if _, err := sm.Apply(ctx, rule, IntValue(4)); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if !fs.upOpts.TargetDependents {
+			t.Error("expected TargetDependents to be set")
+		}
+		if got := fmt.Sprint(fs.upOpts.Target); got != "[urn:asg urn:alarm]" {
+			t.Errorf("targets = %s, want [urn:asg urn:alarm]", got)
+		}
+	})
+
+	t.Run("preview reports extra resources", func(t *testing.T) {
+		fs := newFakeStack(map[string]string{"count": "3"})
+		fs.previewEvents = []events.EngineEvent{
+			stepEvent(apitype.OpUpdate, "urn:asg"),
+			stepEvent(apitype.OpUpdate, "urn:alarm"),
+			stepEvent(apitype.OpSame, "urn:unchanged"),
+			stepEvent(apitype.OpUpdate, "urn:deployment"),
+			stepEvent(apitype.OpUpdate, "urn:deployment"),
+		}
+		sm := newFakeStateManager(fs)
+
+		res, err := sm.Preview(ctx, rule, IntValue(4))
+		if err != nil {
+			t.Fatalf("Preview() error = %v", err)
+		}
+		if !fs.previewOpts.TargetDependents {
+			t.Error("expected TargetDependents to be set")
+		}
+		if got := fmt.Sprint(res.Extra); got != "[urn:deployment]" {
+			t.Errorf("extra = %s, want [urn:deployment]", got)
+		}
+	})
+
+	t.Run("preview stages the new config", func(t *testing.T) {
+		rule := rule
+		rule.DerivedKeys = []DerivedKey{{ConfigKey: "maxCount", Value: "desired * 2"}}
+		fs := newFakeStack(map[string]string{"count": "3"})
+		// Only the staged values change the downstream deployment.
+		fs.previewFn = func(config map[string]auto.ConfigValue) []events.EngineEvent {
+			if config["count"].Value == "4" && config["maxCount"].Value == "8" {
+				return []events.EngineEvent{stepEvent(apitype.OpUpdate, "urn:asg"), stepEvent(apitype.OpUpdate, "urn:deployment")}
+			}
+			return []events.EngineEvent{stepEvent(apitype.OpSame, "urn:asg")}
+		}
+		sm := newFakeStateManager(fs)
+
+		res, err := sm.Preview(ctx, rule, IntValue(4))
+		if err != nil {
+			t.Fatalf("Preview() error = %v", err)
+		}
+		if got := fmt.Sprint(res.Extra); got != "[urn:deployment]" {
+			t.Errorf("extra = %s, want [urn:deployment]", got)
+		}
+		if got := fs.config["count"].Value; got != "3" {
+			t.Errorf("config count = %s after preview, want 3", got)
+		}
+		if v, ok := fs.config["maxCount"]; ok {
+			t.Errorf("maxCount = %s after preview, want it removed", v.Value)
+		}
+		if fs.upCalls != 0 {
+			t.Errorf("up calls = %d, want 0", fs.upCalls)
+		}
+	})
+
+	t.Run("preview copies the stack config file", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Pulumi.dev.yaml"), []byte("config:\n  infra:count: \"3\"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		fs := newFakeStack(map[string]string{"count": "3"})
+		fs.workDir = dir
+		var staged string
+		fs.previewFn = func(map[string]auto.ConfigValue) []events.EngineEvent {
+			data, err := os.ReadFile(fs.previewOpts.ConfigFile)
+			if err != nil {
+				t.Errorf("staged config: %v", err)
+			}
+			staged = string(data)
+			return nil
+		}
+		sm := newFakeStateManager(fs)
+
+		if _, err := sm.Preview(ctx, rule, IntValue(4)); err != nil {
+			t.Fatalf("Preview() error = %v", err)
+		}
+		if !strings.Contains(staged, "infra:count") {
+			t.Errorf("staged config = %q, want a copy of Pulumi.dev.yaml", staged)
+		}
+		if _, err := os.Stat(fs.previewOpts.ConfigFile); !os.IsNotExist(err) {
+			t.Errorf("staged config file still exists after preview (err = %v)", err)
+		}
+		if got := fs.config["count"].Value; got != "3" {
+			t.Errorf("config count = %s after preview, want 3", got)
+		}
+	})
+}
+
+func TestFullUpAndHistory(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(nil)
+	fs.upErrs = []error{conflictError()}
+	fs.history = []auto.UpdateSummary{{Version: 3}, {Version: 2}, {Version: 1}}
+	sm := newFakeStateManager(fs)
+	sm.Clock = &fakeClock{}
+
+	if _, err := sm.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if fs.upCalls != 2 || len(fs.upOpts.Target) != 0 {
+		t.Errorf("upCalls = %d, targets = %v; want a retried untargeted up", fs.upCalls, fs.upOpts.Target)
+	}
+
+	updates, err := sm.History(ctx, 2)
+	if err != nil || len(updates) != 2 || updates[0].Version != 3 {
+		t.Errorf("History() = %v, %v", updates, err)
+	}
+}
+
+func TestGetCurrentValueFrom(t *testing.T) {
+	const (
+		asgURN = "urn:pulumi:dev::app::aws:autoscaling/group:Group::workers"
+		eksURN = "urn:pulumi:dev::app::aws:eks/nodeGroup:NodeGroup::workers"
+	)
+	fs := newFakeStack(nil) // no config: reading workerCount would fail
+	fs.resources = []apitype.ResourceV3{
+		{URN: resource.URN(asgURN), Outputs: map[string]any{"desiredCapacity": 4.0}},
+		{URN: resource.URN(eksURN), Outputs: map[string]any{"scalingConfig": map[string]any{"desiredSize": 6.0}}},
+	}
+	fs.outputs = auto.OutputMap{
+		"pools":  {Value: map[string]any{"workers": map[string]any{"size": 7.0}}},
+		"sealed": {Value: map[string]any{"size": 8.0}, Secret: true},
+	}
+	sm := newFakeStateManager(fs)
+
+	tests := []struct {
+		name       string
+		from       *CurrentSource
+		secret     bool
+		want       float64
+		wantSecret bool
+	}{
+		{name: "first target property", from: &CurrentSource{Property: "desiredCapacity"}, want: 4},
+		{name: "other resource", from: &CurrentSource{Property: "scalingConfig.desiredSize", Resource: eksURN}, want: 6},
+		{name: "stack output", from: &CurrentSource{Output: "pools.workers.size"}, want: 7},
+		{name: "secret rule property", from: &CurrentSource{Property: "desiredCapacity"}, secret: true, want: 4, wantSecret: true},
+		{name: "secret rule output", from: &CurrentSource{Output: "pools.workers.size"}, secret: true, want: 7, wantSecret: true},
+		{name: "secret output", from: &CurrentSource{Output: "sealed.size"}, want: 8, wantSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := ScalingRule{PoolName: "workers", TargetURN: asgURN, ConfigKey: "workerCount", CurrentFrom: tt.from, Secret: tt.secret}
+			got, err := sm.GetCurrentValue(context.Background(), rule)
+			if err != nil {
+				t.Fatalf("GetCurrentValue() error = %v", err)
+			}
+			if got.Number != tt.want || got.Type != ValueInt || got.Secret != tt.wantSecret {
+				t.Errorf("GetCurrentValue() = %+v, want %v (secret %v)", got, tt.want, tt.wantSecret)
+			}
+		})
+	}
+
+	rule := ScalingRule{PoolName: "workers", TargetURN: asgURN, ConfigKey: "workerCount"}
+	if _, err := sm.GetCurrentValue(context.Background(), rule); err == nil {
+		t.Error("expected config read to fail without currentFrom")
+	}
+}
+
+func TestConfigMissing(t *testing.T) {
+	ctx := context.Background()
+	getErr := errors.New("exit status 255")
+	fs := newFakeStack(map[string]string{
+		"infra:desiredSize": "3",
+		"infra:nodeGroup":   `{"minSize": 2, "labels": {"tier": "web"}}`,
+	})
+
+	tests := []struct {
+		name        string
+		key         string
+		path        bool
+		wantMissing bool
+	}{
+		{name: "unset key", key: "maxSize", wantMissing: true},
+		{name: "set key without namespace", key: "desiredSize"},
+		{name: "set key with namespace", key: "infra:desiredSize"},
+		{name: "unset path root", key: "pool.minSize", path: true, wantMissing: true},
+		{name: "unset path leaf", key: "nodeGroup.maxSize", path: true, wantMissing: true},
+		{name: "set path leaf", key: "nodeGroup.minSize", path: true},
+		{name: "set nested path leaf", key: "nodeGroup.labels.tier", path: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing, err := configMissing(ctx, fs, tt.key, tt.path, getErr)
+			if missing != tt.wantMissing {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+			// A key that exists means the read failed for some other reason.
+			if wantErr := !tt.wantMissing; (err != nil) != wantErr {
+				t.Errorf("err = %v, want error %v", err, wantErr)
+			}
+		})
+	}
 }
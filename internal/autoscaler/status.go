@@ -0,0 +1,255 @@
+package autoscaler
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+// PoolStatus is the runtime state of one pool.
+type PoolStatus struct {
+	Pool string      `json:"pool"`
+	Rule ScalingRule `json:"rule"`
+
+	// Current is read from the stack when the status is requested, at most every
+	// currentCacheTTL, and CurrentAt is when. CurrentError is set instead when it can't be read.
+	Current      Value     `json:"current"`
+	CurrentError string    `json:"currentError,omitempty"`
+	CurrentAt    time.Time `json:"currentAt,omitzero"`
+
+	LastScaled        time.Time    `json:"lastScaled,omitzero"`
+	CooldownRemaining float64      `json:"cooldownRemainingSeconds"`
+	InFlight          *InFlightJob `json:"inFlight,omitempty"`
+	Paused            bool         `json:"paused"`
+	Drifted           bool         `json:"drifted"`
+	LastError         string       `json:"lastError,omitempty"`
+	LastErrorAt       time.Time    `json:"lastErrorAt,omitzero"`
+	Pinned            *PinnedState `json:"pinned,omitempty"`
+}
+
+// InFlightJob is the intent the engine is processing.
+type InFlightJob struct {
+	JobID     string                `json:"jobId"`
+	Pool      string                `json:"pool"`
+	Action    webhooks.IntentAction `json:"action"`
+	Value     float64               `json:"value"`
+	Text      string                `json:"text,omitempty"`
+	Source    string                `json:"source,omitempty"`
+	DryRun    bool                  `json:"dryRun,omitempty"`
+	StartedAt time.Time             `json:"startedAt"`
+}
+
+// poolState is what the engine remembers about a pool between jobs. It is kept apart from
+// LastScaled and Drifted, which are guarded by the lock held for a whole apply, so status
+// reads never wait for an up to finish.
+type poolState struct {
+	lastScaled  time.Time
+	paused      bool
+	drifted     bool
+	lastError   string
+	lastErrorAt time.Time
+	pinned      *PinnedState
+
+	// The last current value read for a status, kept for currentCacheTTL.
+	current    Value
+	currentErr string
+	currentAt  time.Time
+}
+
+const (
+	// currentCacheTTL is how long a current value read for a status is reused, so clients
+	// polling GET /pools don't run a config read per pool on every request.
+	currentCacheTTL = 10 * time.Second
+
+	// currentReadTimeout bounds each of those reads, so a stuck stack can't hang a status request.
+	currentReadTimeout = 10 * time.Second
+)
+
+// updatePool applies fn to the pool's state.
+func (e *Engine) updatePool(pool string, fn func(*poolState)) {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	if e.pools == nil {
+		e.pools = make(map[string]*poolState)
+	}
+	st, ok := e.pools[pool]
+	if !ok {
+		st = &poolState{}
+		e.pools[pool] = st
+	}
+	fn(st)
+}
+
+// setDrifted marks or clears the pool as drifted.
+func (e *Engine) setDrifted(pool string, drifted bool) {
+	if drifted {
+		e.Drifted[pool] = true
+	} else {
+		delete(e.Drifted, pool)
+	}
+	e.updatePool(pool, func(st *poolState) { st.drifted = drifted })
+}
+
+// Pause makes the engine skip intents for pool until Resume is called. Pauses are kept in
+// memory only and end when the process restarts.
+func (e *Engine) Pause(pool string) {
+	e.updatePool(pool, func(st *poolState) { st.paused = true })
+}
+
+// Resume lets the engine process intents for pool again.
+func (e *Engine) Resume(pool string) {
+	e.updatePool(pool, func(st *poolState) { st.paused = false })
+}
+
+// Paused reports whether pool is paused.
+func (e *Engine) Paused(pool string) bool {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	st, ok := e.pools[pool]
+	return ok && st.paused
+}
+
+// Status returns the state of every pool with a rule, sorted by pool name, reading each
+// current value from the stack.
+func (e *Engine) Status(ctx context.Context) []PoolStatus {
+	rules := e.RulesSnapshot()
+	out := make([]PoolStatus, 0, len(rules))
+	for _, name := range slices.Sorted(maps.Keys(rules)) {
+		out = append(out, e.poolStatus(ctx, rules[name]))
+	}
+	return out
+}
+
+// PoolStatus returns the state of one pool, or false if it has no rule.
+func (e *Engine) PoolStatus(ctx context.Context, pool string) (PoolStatus, bool) {
+	rule, ok := e.rule(pool)
+	if !ok {
+		return PoolStatus{}, false
+	}
+	return e.poolStatus(ctx, rule), true
+}
+
+func (e *Engine) poolStatus(ctx context.Context, rule ScalingRule) PoolStatus {
+	status := PoolStatus{Pool: rule.PoolName, Rule: rule}
+
+	e.statusMu.Lock()
+	if st, ok := e.pools[rule.PoolName]; ok {
+		status.LastScaled = st.lastScaled
+		status.Paused = st.paused
+		status.Drifted = st.drifted
+		status.LastError = st.lastError
+		status.LastErrorAt = st.lastErrorAt
+		if st.pinned != nil {
+			pinned := *st.pinned
+			status.Pinned = &pinned
+		}
+	}
+	if job := e.inFlight; job != nil && job.Pool == rule.PoolName {
+		copied := *job
+		status.InFlight = &copied
+	}
+	e.statusMu.Unlock()
+
+	if !status.LastScaled.IsZero() {
+		until := status.LastScaled.Add(time.Duration(rule.CooldownSeconds) * time.Second)
+		if remaining := time.Until(until); remaining > 0 {
+			status.CooldownRemaining = remaining.Seconds()
+		}
+	}
+
+	if e.State != nil {
+		status.Current, status.CurrentError, status.CurrentAt = e.currentValue(ctx, rule)
+	}
+	return status
+}
+
+// currentValue returns the pool's current value, or the error reading it, and when it was read,
+// reusing a read younger than currentCacheTTL.
+func (e *Engine) currentValue(ctx context.Context, rule ScalingRule) (Value, string, time.Time) {
+	e.statusMu.Lock()
+	if st, ok := e.pools[rule.PoolName]; ok && !st.currentAt.IsZero() && time.Since(st.currentAt) < currentCacheTTL {
+		defer e.statusMu.Unlock()
+		return st.current, st.currentErr, st.currentAt
+	}
+	e.statusMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, currentReadTimeout)
+	defer cancel()
+	current, err := e.State.GetCurrentValue(ctx, rule)
+	var errMsg string
+	if err != nil {
+		current, errMsg = Value{}, err.Error()
+	}
+	now := time.Now()
+	e.updatePool(rule.PoolName, func(st *poolState) {
+		st.current, st.currentErr, st.currentAt = current, errMsg, now
+	})
+	return current, errMsg, now
+}
+
+// forgetCurrent drops the pool's cached current value after something may have changed it.
+func (e *Engine) forgetCurrent(pool string) {
+	e.updatePool(pool, func(st *poolState) { st.currentAt = time.Time{} })
+}
+
+// startJob records intent as in flight; the returned function records the result and clears it.
+func (e *Engine) startJob(jobID string, intent webhooks.ScalingIntent, started time.Time) func(JobResult) {
+	e.statusMu.Lock()
+	e.inFlight = &InFlightJob{
+		JobID:     jobID,
+		Pool:      intent.TargetPool,
+		Action:    intent.Action,
+		Value:     intent.Value,
+		Text:      intent.Text,
+		Source:    intent.Source,
+		DryRun:    intent.DryRun,
+		StartedAt: started,
+	}
+	e.statusMu.Unlock()
+
+	return func(result JobResult) {
+		e.statusMu.Lock()
+		e.inFlight = nil
+		e.statusMu.Unlock()
+
+		if result.Skipped == SkipNoRule {
+			return
+		}
+		e.forgetCurrent(result.Pool)
+		switch result.Status {
+		case JobSucceeded:
+			now := time.Now()
+			e.updatePool(result.Pool, func(st *poolState) { st.lastScaled = now })
+		case JobFailed:
+			e.updatePool(result.Pool, func(st *poolState) {
+				st.lastError = result.Error
+				st.lastErrorAt = result.FinishedAt
+			})
+		}
+	}
+}
+
+// LoadLastScaled seeds each pool's last scaled time from the scaler's successful updates
+// among the stack's last depth updates, e.g. for a process that hasn't scaled anything itself.
+func (e *Engine) LoadLastScaled(ctx context.Context, depth int) error {
+	updates, err := e.State.ScalerUpdates(ctx, "", depth)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, u := range updates {
+		if u.Result != "succeeded" || u.FinishedAt.IsZero() {
+			continue
+		}
+		if last, ok := e.LastScaled[u.Pool]; ok && !u.FinishedAt.After(last) {
+			continue
+		}
+		e.LastScaled[u.Pool] = u.FinishedAt
+		e.updatePool(u.Pool, func(st *poolState) { st.lastScaled = u.FinishedAt })
+	}
+	return nil
+}
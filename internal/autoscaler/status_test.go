@@ -0,0 +1,114 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestEngineStatus(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3", "instanceType": "m5.large"})
+	sm := newFakeStateManager(fs)
+	sm.Clock = &fakeClock{}
+	workers := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10, CooldownSeconds: 60}
+	types := ScalingRule{PoolName: "types", TargetURN: "urn:b", ConfigKey: "instanceType", Type: ValueString, Allowed: []string{"m5.large"}}
+	e := NewEngine(map[string]ScalingRule{"workers": workers, "types": types}, sm)
+
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 5})
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "types", Action: webhooks.ActionSet, Text: "p4d.24xlarge"})
+
+	statuses := e.Status(ctx)
+	if len(statuses) != 2 || statuses[0].Pool != "types" || statuses[1].Pool != "workers" {
+		t.Fatalf("Status() = %+v, want types and workers sorted by name", statuses)
+	}
+	failed, scaled := statuses[0], statuses[1]
+	if failed.LastError == "" || failed.LastErrorAt.IsZero() || !failed.LastScaled.IsZero() {
+		t.Errorf("failed pool status = %+v, want its error and no last scale", failed)
+	}
+	if !scaled.Current.Equal(IntValue(5)) || scaled.LastScaled.IsZero() || scaled.LastError != "" {
+		t.Errorf("scaled pool status = %+v, want current 5 and a last scale", scaled)
+	}
+	if scaled.CooldownRemaining <= 0 || scaled.CooldownRemaining > 60 {
+		t.Errorf("CooldownRemaining = %v, want within the 60s cooldown", scaled.CooldownRemaining)
+	}
+	if scaled.InFlight != nil {
+		t.Errorf("InFlight = %+v, want nil once the job finished", scaled.InFlight)
+	}
+	if _, ok := e.PoolStatus(ctx, "missing"); ok {
+		t.Error("PoolStatus(missing) found a pool without a rule")
+	}
+}
+
+func TestEngineStatusCachesCurrent(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, newFakeStateManager(fs))
+
+	first, _ := e.PoolStatus(ctx, "workers")
+	fs.config["workerCount"] = auto.ConfigValue{Value: "4"} // changed outside the engine
+	if again, _ := e.PoolStatus(ctx, "workers"); !again.Current.Equal(IntValue(3)) || !again.CurrentAt.Equal(first.CurrentAt) {
+		t.Errorf("second status = %v at %v, want the cached 3 at %v", again.Current, again.CurrentAt, first.CurrentAt)
+	}
+
+	// A scale invalidates the cache.
+	e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 6})
+	if status, _ := e.PoolStatus(ctx, "workers"); !status.Current.Equal(IntValue(6)) {
+		t.Errorf("status after scale = %v, want 6", status.Current)
+	}
+
+	// So does age.
+	fs.config["workerCount"] = auto.ConfigValue{Value: "7"}
+	e.updatePool("workers", func(st *poolState) { st.currentAt = st.currentAt.Add(-currentCacheTTL) })
+	if status, _ := e.PoolStatus(ctx, "workers"); !status.Current.Equal(IntValue(7)) {
+		t.Errorf("status after TTL = %v, want 7", status.Current)
+	}
+}
+
+func TestEnginePause(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	sm := newFakeStateManager(fs)
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, sm)
+
+	e.Pause("workers")
+	if status, _ := e.PoolStatus(ctx, "workers"); !status.Paused {
+		t.Error("PoolStatus().Paused = false after Pause")
+	}
+	if result := e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 5}); result.Skipped != SkipPaused {
+		t.Errorf("paused pool result = %+v, want skipped as paused", result)
+	}
+	if fs.upCalls != 0 {
+		t.Errorf("upCalls = %d while paused, want 0", fs.upCalls)
+	}
+
+	e.Resume("workers")
+	if result := e.ProcessIntent(ctx, webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 5}); result.Status != JobSucceeded {
+		t.Errorf("resumed pool result = %+v, want succeeded", result)
+	}
+}
+
+func TestLoadLastScaled(t *testing.T) {
+	ctx := context.Background()
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	end := "2025-01-01T03:02:30Z"
+	fs.history = []auto.UpdateSummary{
+		{Version: 3, Message: "pulumiscale: scale workers from 3 to 4", Result: "failed", StartTime: "2025-01-01T04:00:00Z", EndTime: &end},
+		{Version: 2, Message: "pulumiscale: scale workers from 2 to 3", Result: "succeeded", StartTime: "2025-01-01T03:00:00Z", EndTime: &end},
+	}
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, newFakeStateManager(fs))
+
+	if err := e.LoadLastScaled(ctx, 10); err != nil {
+		t.Fatalf("LoadLastScaled() error = %v", err)
+	}
+	status, _ := e.PoolStatus(ctx, "workers")
+	if got := status.LastScaled.UTC().Format("2006-01-02T15:04:05Z"); got != end {
+		t.Errorf("LastScaled = %s, want the end of the last successful update %s", got, end)
+	}
+}
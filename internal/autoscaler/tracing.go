@@ -0,0 +1,59 @@
+package autoscaler
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+// tracer is resolved through the global provider, so spans are only recorded
+// once tracing.Setup has installed an exporter.
+var tracer = otel.Tracer("github.com/rshade/pulumi-scale/internal/autoscaler")
+
+// Span attribute keys.
+const (
+	attrPool     = attribute.Key("pulumiscale.pool")
+	attrStack    = attribute.Key("pulumiscale.stack")
+	attrAction   = attribute.Key("pulumiscale.action")
+	attrSource   = attribute.Key("pulumiscale.source")
+	attrDryRun   = attribute.Key("pulumiscale.dry_run")
+	attrStatus   = attribute.Key("pulumiscale.status")
+	attrSkipped  = attribute.Key("pulumiscale.skipped")
+	attrDecision = attribute.Key("pulumiscale.decision")
+	attrAttempt  = attribute.Key("pulumiscale.attempt")
+	attrRetry    = attribute.Key("pulumiscale.conflict_retry")
+	attrConfig   = attribute.Key("pulumiscale.config_key")
+	attrDelay    = attribute.Key("pulumiscale.retry_delay")
+	attrKind     = attribute.Key("pulumiscale.error_kind")
+)
+
+// intentContext continues the trace of the request that queued intent and records
+// the time it spent in the queue. Intents that weren't queued are returned unchanged.
+func intentContext(ctx context.Context, intent webhooks.ScalingIntent) context.Context {
+	if intent.SpanContext.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, intent.SpanContext)
+	}
+	if !intent.QueuedAt.IsZero() {
+		_, span := tracer.Start(ctx, "autoscaler.queue_wait",
+			trace.WithTimestamp(intent.QueuedAt),
+			trace.WithAttributes(attrPool.String(intent.TargetPool)),
+		)
+		span.End(trace.WithTimestamp(time.Now()))
+	}
+	return ctx
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,80 @@
+package autoscaler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+var (
+	recorderOnce sync.Once
+	recorder     *tracetest.SpanRecorder
+	recorderTP   *sdktrace.TracerProvider
+)
+
+// spanRecorder installs a recording tracer provider. The global provider can only be
+// delegated to once, so every test shares it and filters spans by trace ID.
+func spanRecorder() (*tracetest.SpanRecorder, trace.Tracer) {
+	recorderOnce.Do(func() {
+		recorder = tracetest.NewSpanRecorder()
+		recorderTP = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		otel.SetTracerProvider(recorderTP)
+	})
+	return recorder, recorderTP.Tracer("test")
+}
+
+func TestProcessIntentTracing(t *testing.T) {
+	rec, testTracer := spanRecorder()
+	fs := newFakeStack(map[string]string{"workerCount": "3"})
+	fs.upErrs = []error{conflictError()}
+	sm := newFakeStateManager(fs)
+	sm.Clock = &fakeClock{}
+	rule := ScalingRule{PoolName: "workers", TargetURN: "urn:a", ConfigKey: "workerCount", Min: 1, Max: 10}
+	e := NewEngine(map[string]ScalingRule{"workers": rule}, sm)
+
+	// The webhook handler's span ends before the engine picks the intent up.
+	reqCtx, reqSpan := testTracer.Start(context.Background(), "POST /webhook")
+	intent := webhooks.ScalingIntent{TargetPool: "workers", Action: webhooks.ActionSet, Value: 5}.Queued(reqCtx)
+	reqSpan.End()
+
+	if result := e.ProcessIntent(context.Background(), intent); result.Status != JobSucceeded {
+		t.Fatalf("ProcessIntent() = %+v", result)
+	}
+
+	traceID := reqSpan.SpanContext().TraceID()
+	parents := map[string]trace.SpanID{}
+	var names []string
+	for _, span := range rec.Ended() {
+		if span.SpanContext().TraceID() != traceID {
+			continue
+		}
+		names = append(names, span.Name())
+		parents[span.Name()] = span.Parent().SpanID()
+	}
+
+	for _, want := range []string{"autoscaler.queue_wait", "autoscaler.process_intent", "autoscaler.current_value", "pulumi.open_stack", "pulumi.get_config", "pulumi.set_config", "autoscaler.retry_backoff"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("span %q not recorded; got %v", want, names)
+		}
+	}
+	var ups int
+	for _, name := range names {
+		if name == "pulumi.up" {
+			ups++
+		}
+	}
+	if ups != 2 {
+		t.Errorf("recorded %d pulumi.up spans, want one per conflict retry (2)", ups)
+	}
+	if parents["autoscaler.process_intent"] != reqSpan.SpanContext().SpanID() {
+		t.Errorf("process_intent is not a child of the webhook span")
+	}
+}
@@ -1,31 +1,277 @@
 package autoscaler
 
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
 type ScalingStrategy string
 
 const (
-    StrategyIncremental ScalingStrategy = "incremental" // +/- delta
-    StrategyAbsolute    ScalingStrategy = "absolute"    // set to value
+	StrategyIncremental ScalingStrategy = "incremental" // +/- delta
+	StrategyAbsolute    ScalingStrategy = "absolute"    // set to value
+)
+
+// FailurePolicy decides what happens to the stack config when the targeted up fails.
+type FailurePolicy string
+
+const (
+	FailureRollback FailurePolicy = "rollback" // restore the previous config value
+	FailureLeave    FailurePolicy = "leave"    // keep the new value and mark the pool drifted
+	FailureRetry    FailurePolicy = "retry"    // retry the up with backoff, then roll back
 )
 
 type ScalingRule struct {
-    // The key in the user's stack output map (e.g., "worker-pool")
-    PoolName string `json:"-"` 
+	// The key in the user's stack output map (e.g., "worker-pool")
+	PoolName string `json:"-"`
+
+	// The Pulumi URN of the resource to target with `pulumi up -t`
+	// Example: "urn:pulumi:dev::my-stack::aws:autoscaling/group:Group::workers"
+	TargetURN string `json:"targetUrn"`
+
+	// (Optional) Additional URNs updated in the same targeted up,
+	// e.g. a launch template that belongs to the group.
+	TargetURNs []string `json:"targetUrns"`
+
+	// (Optional) Also update resources that depend on the targets (`pulumi up --target-dependents`),
+	// e.g. a Deployment reading the node count.
+	TargetDependents bool `json:"targetDependents"`
+
+	// (Optional) Consumers of the scaled value to update alongside the targets,
+	// e.g. an alarm whose threshold follows the pool size.
+	AlsoTarget []string `json:"alsoTarget"`
+
+	// The Pulumi Config key to update
+	// Example: "workerCount", or "nodeGroups.workers.desired" with Path set
+	ConfigKey string `json:"configKey"`
+
+	// (Optional) Extra config keys computed from the desired value and written together with ConfigKey.
+	DerivedKeys []DerivedKey `json:"derivedKeys"`
+
+	// (Optional) Treat ConfigKey as a path into structured config.
+	Path bool `json:"path"`
+
+	// (Optional) Store the value as a Pulumi secret.
+	Secret bool `json:"secret"`
+
+	// (Optional) How the config value is parsed: "int" (default), "float" or "string".
+	Type ValueType `json:"type"`
+
+	// Scaling limits (Guardrails) for numeric types
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
 
-    // The Pulumi URN of the resource to target with `pulumi up -t`
-    // Example: "urn:pulumi:dev::my-stack::aws:autoscaling/group:Group::workers"
-    TargetURN string `json:"targetUrn"`
+	// (Optional) Permitted values for the "string" type. Empty allows any value.
+	Allowed []string `json:"allowed"`
+
+	// Cooldown in seconds before allowing another scale event
+	CooldownSeconds int `json:"cooldown"`
+
+	// (Optional) Seconds a numeric pool may stay clamped at min or max while intents ask to go
+	// past it before the engine raises a pinned alert. Defaults to the engine's PinnedAfter.
+	PinnedAlertSeconds int `json:"pinnedAlert"`
+
+	// (Optional) Strategy defaults. Webhooks can override or imply this.
+	Strategy ScalingStrategy `json:"strategy"`
+
+	// (Optional) What to do with the config when the up fails. Defaults to "rollback".
+	OnFailure FailurePolicy `json:"onFailure"`
+
+	// (Optional) Extra up attempts for the "retry" policy. Defaults to 3; -1 disables them.
+	FailureRetries int `json:"failureRetries"`
+
+	// (Optional) Backoff for concurrent update conflicts and the "retry" policy.
+	Retry RetryPolicy `json:"retry"`
+
+	// (Optional) Where the current value is read from. Defaults to ConfigKey.
+	CurrentFrom *CurrentSource `json:"currentFrom"`
+
+	// (Optional) Compare the config value with the target resource's live state.
+	Drift *DriftConfig `json:"drift"`
+}
+
+// DriftPolicy decides what happens when a pool's config value and live resource state differ.
+type DriftPolicy string
+
+const (
+	DriftReport  DriftPolicy = "report"  // only report the drift
+	DriftAdopt   DriftPolicy = "adopt"   // write the live value to the config
+	DriftCorrect DriftPolicy = "correct" // run a targeted up to restore the config value
+)
+
+// DriftConfig tells the drift checker where the live value of a pool lives.
+type DriftConfig struct {
+	// Output property of the first target resource holding the live value,
+	// e.g. "desiredCapacity" or "scalingConfig.desiredSize".
+	Property string `json:"property"`
+
+	// (Optional) Run a targeted refresh before reading the property so it reflects
+	// changes made outside Pulumi. Without it, the last deployed state is compared.
+	Refresh bool `json:"refresh"`
+
+	// (Optional) "report" (default), "adopt" or "correct".
+	Policy DriftPolicy `json:"policy"`
+}
 
-    // The Pulumi Config key to update
-    // Example: "workerCount"
-    ConfigKey string `json:"configKey"`
+// CurrentSource reads a pool's current value from deployed state instead of its config key,
+// so delta scaling works from reality even when the config is stale or unreadable.
+// Exactly one of Property or Output is set.
+type CurrentSource struct {
+	// Output property of a resource in the stack state, e.g. "desiredCapacity".
+	Property string `json:"property"`
 
-    // Scaling limits (Guardrails)
-    Min int `json:"min"`
-    Max int `json:"max"`
+	// (Optional) URN of the resource holding Property. Defaults to the rule's first target.
+	Resource string `json:"resource"`
 
-    // Cooldown in seconds before allowing another scale event
-    CooldownSeconds int `json:"cooldown"`
+	// Name of a stack output, optionally followed by a dot-separated path into it,
+	// e.g. "workerCount" or "pools.workers.size".
+	Output string `json:"output"`
+}
+
+// DerivedKey is an extra config key whose value is derived from the pool's desired value.
+type DerivedKey struct {
+	// The Pulumi Config key to update, e.g. "maxSize"
+	ConfigKey string `json:"configKey"`
+
+	// Arithmetic over desired, current, min and max, e.g. "desired + 2" or "max(desired, 3)".
+	Value string `json:"value"`
+
+	// (Optional) Treat ConfigKey as a config path.
+	Path bool `json:"path"`
+
+	// (Optional) Store the value as a Pulumi secret.
+	Secret bool `json:"secret"`
+
+	// (Optional) "int" (default) or "float".
+	Type ValueType `json:"type"`
+}
+
+// targets returns every URN the rule's targeted up should include, without duplicates.
+func (r ScalingRule) targets() []string {
+	var urns []string
+	for _, urn := range append([]string{r.TargetURN}, r.TargetURNs...) {
+		if urn != "" && !slices.Contains(urns, urn) {
+			urns = append(urns, urn)
+		}
+	}
+	return urns
+}
+
+// allTargets returns targets plus the rule's AlsoTarget URNs.
+func (r ScalingRule) allTargets() []string {
+	urns := r.targets()
+	for _, urn := range r.AlsoTarget {
+		if urn != "" && !slices.Contains(urns, urn) {
+			urns = append(urns, urn)
+		}
+	}
+	return urns
+}
+
+// deriveValues evaluates the rule's DerivedKeys for a desired value.
+func (r ScalingRule) deriveValues(desired, current Value) (map[string]Value, error) {
+	if len(r.DerivedKeys) == 0 {
+		return nil, nil
+	}
+	vars := map[string]float64{
+		"desired": desired.Number,
+		"current": current.Number,
+		"min":     r.Min,
+		"max":     r.Max,
+	}
+	out := make(map[string]Value, len(r.DerivedKeys))
+	for _, dk := range r.DerivedKeys {
+		e, err := parseExpr(dk.Value)
+		if err != nil {
+			return nil, fmt.Errorf("derived key %s: %w", dk.ConfigKey, err)
+		}
+		f, err := e.eval(vars)
+		if err != nil {
+			return nil, fmt.Errorf("derived key %s: %w", dk.ConfigKey, err)
+		}
+		v := NumberValue(dk.Type, f)
+		v.Secret = dk.Secret
+		out[dk.ConfigKey] = v
+	}
+	return out, nil
+}
+
+// valueType returns the rule's value type with the default applied.
+func (r ScalingRule) valueType() ValueType {
+	if r.Type == "" {
+		return ValueInt
+	}
+	return r.Type
+}
+
+// configOptions returns the Automation API options for reading and writing ConfigKey.
+func (r ScalingRule) configOptions() *auto.ConfigOptions {
+	return &auto.ConfigOptions{Path: r.Path}
+}
+
+// JobStatus is the final state of a scaling job.
+type JobStatus string
+
+const (
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobPreviewed JobStatus = "previewed"
+	JobSkipped   JobStatus = "skipped"
+)
+
+// FailureDecision records how a failed up was handled under the rule's FailurePolicy.
+type FailureDecision string
+
+const (
+	DecisionNone           FailureDecision = ""
+	DecisionRolledBack     FailureDecision = "rolled_back"
+	DecisionRollbackFailed FailureDecision = "rollback_failed"
+	DecisionDrifted        FailureDecision = "drifted"
+	DecisionRetried        FailureDecision = "retried"
+)
+
+// SkipReason records why a job was skipped without touching the stack.
+type SkipReason string
+
+const (
+	SkipNoRule    SkipReason = "no_rule"
+	SkipCooldown  SkipReason = "cooldown"
+	SkipUnchanged SkipReason = "unchanged"
+	SkipPaused    SkipReason = "paused"
+)
+
+// ClampBound records which guardrail a numeric target was clamped to.
+type ClampBound string
+
+const (
+	ClampMin ClampBound = "min"
+	ClampMax ClampBound = "max"
+)
 
-    // (Optional) Strategy defaults. Webhooks can override or imply this.
-    Strategy ScalingStrategy `json:"strategy"`
+// JobResult is the outcome of processing a single ScalingIntent.
+type JobResult struct {
+	JobID           string           `json:"jobId,omitempty"`
+	Pool            string           `json:"pool"`
+	Previous        Value            `json:"previous"`
+	Target          Value            `json:"target"`
+	Status          JobStatus        `json:"status"`
+	Skipped         SkipReason       `json:"skipped,omitempty"`
+	CooldownUntil   time.Time        `json:"cooldownUntil,omitzero"` // when the cooldown that skipped the job ends
+	Clamped         ClampBound       `json:"clamped,omitempty"`
+	Requested       float64          `json:"requested,omitempty"` // unclamped target, when Clamped is set and the pool isn't secret
+	Decision        FailureDecision  `json:"decision,omitempty"`
+	Attempts        int              `json:"attempts"`
+	ConflictRetries int              `json:"conflictRetries,omitempty"` // retries after concurrent update conflicts
+	Error           string           `json:"error,omitempty"`
+	ErrorKind       ErrorKind        `json:"errorKind,omitempty"`
+	Derived         map[string]Value `json:"derived,omitempty"`
+	UpdateVersion   int              `json:"updateVersion,omitempty"` // stack update version of the successful up
+	Commit          string           `json:"commit,omitempty"`        // program commit, for git-sourced stacks
+	PinnedSince     time.Time        `json:"pinnedSince,omitzero"`    // since when the pool has been clamped to the same bound
+	PinnedAlert     bool             `json:"pinnedAlert,omitempty"`   // the pool has now been pinned longer than its alert duration
+	StartedAt       time.Time        `json:"startedAt"`
+	FinishedAt      time.Time        `json:"finishedAt"`
 }
@@ -0,0 +1,167 @@
+package autoscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValueType describes how a pool's config value is parsed and written.
+type ValueType string
+
+const (
+	ValueInt    ValueType = "int"    // whole numbers, e.g. node counts (default)
+	ValueFloat  ValueType = "float"  // fractional values, e.g. GPU shares
+	ValueString ValueType = "string" // opaque values, e.g. instance types
+)
+
+// Value is a typed pool setting read from or written to stack config.
+// Numeric types use Number, ValueString uses Text.
+type Value struct {
+	Type   ValueType
+	Number float64
+	Text   string
+
+	// Secret values are written as Pulumi secrets and redacted by String and MarshalJSON.
+	Secret bool
+}
+
+// IntValue returns an integer Value.
+func IntValue(n int) Value {
+	return Value{Type: ValueInt, Number: float64(n)}
+}
+
+// FloatValue returns a fractional Value.
+func FloatValue(f float64) Value {
+	return Value{Type: ValueFloat, Number: f}
+}
+
+// StringValue returns a string Value.
+func StringValue(s string) Value {
+	return Value{Type: ValueString, Text: s}
+}
+
+// NumberValue returns a numeric Value of type t, rounding to the nearest whole number for ValueInt.
+func NumberValue(t ValueType, f float64) Value {
+	if t == ValueInt || t == "" {
+		return Value{Type: ValueInt, Number: math.Round(f)}
+	}
+	return Value{Type: t, Number: f}
+}
+
+// IsNumeric reports whether the value can be clamped and incremented.
+func (v Value) IsNumeric() bool {
+	return v.Type != ValueString
+}
+
+// Raw returns the value as it is stored in stack config.
+func (v Value) Raw() string {
+	switch v.Type {
+	case ValueString:
+		return v.Text
+	case ValueFloat:
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
+	default:
+		return strconv.FormatInt(int64(v.Number), 10)
+	}
+}
+
+// redacted replaces secret values in logs and JSON.
+const redacted = "[secret]"
+
+// String returns the value for logs, redacting secrets.
+func (v Value) String() string {
+	if v.Secret {
+		return redacted
+	}
+	return v.Raw()
+}
+
+// Equal compares the typed contents of two values, ignoring secrecy.
+func (v Value) Equal(o Value) bool {
+	if v.IsNumeric() != o.IsNumeric() {
+		return false
+	}
+	if v.IsNumeric() {
+		return v.Number == o.Number
+	}
+	return v.Text == o.Text
+}
+
+// MarshalJSON renders numeric values as JSON numbers and strings as JSON strings.
+// Float values always have a decimal point (3.0), so they decode as floats again.
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch {
+	case v.Secret:
+		return json.Marshal(redacted)
+	case v.Type == "" && v.Number == 0:
+		return []byte("null"), nil
+	case v.Type == ValueFloat:
+		if math.IsInf(v.Number, 0) || math.IsNaN(v.Number) {
+			return nil, fmt.Errorf("cannot encode %v as JSON", v.Number)
+		}
+		s := strconv.FormatFloat(v.Number, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return []byte(s), nil
+	case v.IsNumeric():
+		return json.Marshal(v.Number)
+	default:
+		return json.Marshal(v.Text)
+	}
+}
+
+// UnmarshalJSON reads values written by MarshalJSON. Numbers written with a decimal point or
+// a fraction become ValueFloat and other numbers ValueInt; redacted secrets come back as the
+// string "[secret]".
+func (v *Value) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	switch x := raw.(type) {
+	case nil:
+		*v = Value{}
+	case json.Number:
+		f, err := x.Float64()
+		if err != nil {
+			return err
+		}
+		if strings.Contains(x.String(), ".") || f != math.Trunc(f) {
+			*v = FloatValue(f)
+		} else {
+			*v = NumberValue(ValueInt, f)
+		}
+	case string:
+		*v = StringValue(x)
+	default:
+		return fmt.Errorf("cannot decode %s as a pool value", data)
+	}
+	return nil
+}
+
+// parseValue parses a raw config string as type t.
+func parseValue(t ValueType, raw string) (Value, error) {
+	raw = strings.TrimSpace(raw)
+	switch t {
+	case ValueString:
+		return StringValue(raw), nil
+	case ValueFloat:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("failed to parse config value '%s' as float: %w", raw, err)
+		}
+		return FloatValue(f), nil
+	default:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return Value{}, fmt.Errorf("failed to parse config value '%s' as int: %w", raw, err)
+		}
+		return IntValue(n), nil
+	}
+}
@@ -0,0 +1,246 @@
+// Package metrics exports Prometheus metrics about the autoscaler itself.
+package metrics
+
+import (
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+const namespace = "pulumiscale"
+
+var (
+	poolLabels = []string{"stack", "pool"}
+
+	minDesc     = prometheus.NewDesc(namespace+"_pool_min", "Lower guardrail of a numeric pool.", poolLabels, nil)
+	maxDesc     = prometheus.NewDesc(namespace+"_pool_max", "Upper guardrail of a numeric pool.", poolLabels, nil)
+	currentDesc = prometheus.NewDesc(namespace+"_pool_current", "Last known value of a numeric pool.", poolLabels, nil)
+	desiredDesc = prometheus.NewDesc(namespace+"_pool_desired", "Target of the last scaling job for a numeric pool.", poolLabels, nil)
+	driftDesc   = prometheus.NewDesc(namespace+"_pool_drifted", "Whether the last drift check found the pool's config and live state differ.", poolLabels, nil)
+	pinnedDesc  = prometheus.NewDesc(namespace+"_pool_pinned_seconds", "How long a numeric pool has been clamped to a guardrail by intents asking to go past it.", []string{"stack", "pool", "bound"}, nil)
+	queueDesc   = prometheus.NewDesc(namespace+"_queue_depth", "Intents waiting in the engine's queue.", []string{"stack"}, nil)
+	queueCap    = prometheus.NewDesc(namespace+"_queue_capacity", "Size of the engine's intent queue.", []string{"stack"}, nil)
+)
+
+// Metrics holds the autoscaler's collectors. Stacks are added with AddEngine and
+// AddDriftChecker; pool gauges are read from the engine's current rules at scrape
+// time, so removed pools disappear from the output.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	intents       *prometheus.CounterVec
+	cooldownSkips *prometheus.CounterVec
+	clamps        *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	retries       *prometheus.CounterVec
+	pinnedAlerts  *prometheus.CounterVec
+
+	mu     sync.Mutex
+	stacks map[string]*stackMetrics
+}
+
+// stackMetrics is the scrape-time state of one stack.
+type stackMetrics struct {
+	engine *autoscaler.Engine
+	drift  *autoscaler.DriftChecker
+
+	mu      sync.Mutex
+	current map[string]float64
+	desired map[string]float64
+}
+
+// New creates the collectors and registers them, along with the Go runtime and
+// process collectors, on a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		intents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "intents_total",
+			Help:      "Intents processed, by source and outcome.",
+		}, []string{"stack", "pool", "source", "outcome"}),
+		cooldownSkips: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cooldown_skips_total",
+			Help:      "Intents skipped because the pool was in cooldown.",
+		}, poolLabels),
+		clamps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "clamps_total",
+			Help:      "Targets clamped to a pool's min or max.",
+		}, []string{"stack", "pool", "bound"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_duration_seconds",
+			Help:      "Duration of scaling jobs that reached the stack, by operation (apply or preview).",
+			Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200},
+		}, []string{"stack", "pool", "operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Up retries, by kind (conflict for concurrent updates, failure for the retry policy).",
+		}, []string{"stack", "pool", "kind"}),
+		pinnedAlerts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pinned_alerts_total",
+			Help:      "Pools that stayed pinned at a guardrail for longer than their alert duration.",
+		}, []string{"stack", "pool", "bound"}),
+		stacks: make(map[string]*stackMetrics),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.intents, m.cooldownSkips, m.clamps, m.duration, m.retries, m.pinnedAlerts,
+		m,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// AddEngine records the jobs of a stack's engine and exports its pools and queue.
+func (m *Metrics) AddEngine(stack string, e *autoscaler.Engine) {
+	st := m.stack(stack)
+	st.mu.Lock()
+	st.engine = e
+	st.mu.Unlock()
+	e.Observe(func(intent webhooks.ScalingIntent, rule autoscaler.ScalingRule, result autoscaler.JobResult) {
+		m.observe(stack, st, intent, rule, result)
+	})
+}
+
+// AddDriftChecker exports the latest drift status of a stack's pools.
+func (m *Metrics) AddDriftChecker(stack string, d *autoscaler.DriftChecker) {
+	st := m.stack(stack)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.drift = d
+}
+
+func (m *Metrics) stack(name string) *stackMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.stacks[name]
+	if !ok {
+		st = &stackMetrics{current: make(map[string]float64), desired: make(map[string]float64)}
+		m.stacks[name] = st
+	}
+	return st
+}
+
+func (m *Metrics) observe(stack string, st *stackMetrics, intent webhooks.ScalingIntent, rule autoscaler.ScalingRule, result autoscaler.JobResult) {
+	source := intent.Source
+	if source == "" {
+		source = "unknown"
+	}
+	pool := result.Pool
+	m.intents.WithLabelValues(stack, pool, source, string(result.Status)).Inc()
+
+	if result.Skipped == autoscaler.SkipCooldown {
+		m.cooldownSkips.WithLabelValues(stack, pool).Inc()
+	}
+	if result.Clamped != "" {
+		m.clamps.WithLabelValues(stack, pool, string(result.Clamped)).Inc()
+	}
+	if result.PinnedAlert {
+		m.pinnedAlerts.WithLabelValues(stack, pool, string(result.Clamped)).Inc()
+	}
+	if result.ConflictRetries > 0 {
+		m.retries.WithLabelValues(stack, pool, "conflict").Add(float64(result.ConflictRetries))
+	}
+	if result.Attempts > 1 {
+		m.retries.WithLabelValues(stack, pool, "failure").Add(float64(result.Attempts - 1))
+	}
+
+	switch {
+	case result.Status == autoscaler.JobPreviewed:
+		m.duration.WithLabelValues(stack, pool, "preview").Observe(result.FinishedAt.Sub(result.StartedAt).Seconds())
+	case result.Attempts > 0:
+		m.duration.WithLabelValues(stack, pool, "apply").Observe(result.FinishedAt.Sub(result.StartedAt).Seconds())
+	}
+
+	// Pool values are only tracked for numeric pools, and never for secret ones.
+	if rule.PoolName == "" || rule.Type == autoscaler.ValueString || rule.Secret || result.Target.Type == "" {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if result.Status != autoscaler.JobPreviewed {
+		st.desired[pool] = result.Target.Number
+	}
+	switch {
+	case result.Status == autoscaler.JobSucceeded:
+		st.current[pool] = result.Target.Number
+	case result.Previous.Type != "" && !result.Previous.Secret:
+		st.current[pool] = result.Previous.Number
+	}
+}
+
+// Describe implements prometheus.Collector for the scrape-time gauges.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{minDesc, maxDesc, currentDesc, desiredDesc, driftDesc, pinnedDesc, queueDesc, queueCap} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector for the scrape-time gauges.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	stacks := maps.Clone(m.stacks)
+	m.mu.Unlock()
+
+	for name, st := range stacks {
+		st.collect(name, ch)
+	}
+}
+
+func (st *stackMetrics) collect(stack string, ch chan<- prometheus.Metric) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if e := st.engine; e != nil {
+		ch <- prometheus.MustNewConstMetric(queueDesc, prometheus.GaugeValue, float64(len(e.IntentChan)), stack)
+		ch <- prometheus.MustNewConstMetric(queueCap, prometheus.GaugeValue, float64(cap(e.IntentChan)), stack)
+
+		for pool, rule := range e.RulesSnapshot() {
+			if rule.Type == autoscaler.ValueString {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(minDesc, prometheus.GaugeValue, rule.Min, stack, pool)
+			ch <- prometheus.MustNewConstMetric(maxDesc, prometheus.GaugeValue, rule.Max, stack, pool)
+			if v, ok := st.current[pool]; ok {
+				ch <- prometheus.MustNewConstMetric(currentDesc, prometheus.GaugeValue, v, stack, pool)
+			}
+			if v, ok := st.desired[pool]; ok {
+				ch <- prometheus.MustNewConstMetric(desiredDesc, prometheus.GaugeValue, v, stack, pool)
+			}
+			if pinned, ok := e.Pinned(pool); ok {
+				ch <- prometheus.MustNewConstMetric(pinnedDesc, prometheus.GaugeValue, time.Since(pinned.Since).Seconds(), stack, pool, string(pinned.Bound))
+			}
+		}
+	}
+
+	if d := st.drift; d != nil {
+		for _, status := range d.Statuses() {
+			if status.Error != "" {
+				continue
+			}
+			drifted := 0.0
+			if status.Drifted {
+				drifted = 1
+			}
+			ch <- prometheus.MustNewConstMetric(driftDesc, prometheus.GaugeValue, drifted, stack, status.Pool)
+		}
+	}
+}
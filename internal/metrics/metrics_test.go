@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+func TestObserve(t *testing.T) {
+	m := New()
+	rule := autoscaler.ScalingRule{PoolName: "workers", Min: 1, Max: 5}
+	st := m.stack("dev")
+	started := time.Now()
+
+	m.observe("dev", st, webhooks.ScalingIntent{TargetPool: "workers", Source: "cloudwatch"}, rule, autoscaler.JobResult{
+		Pool:            "workers",
+		Previous:        autoscaler.IntValue(3),
+		Target:          autoscaler.IntValue(5),
+		Status:          autoscaler.JobSucceeded,
+		Clamped:         autoscaler.ClampMax,
+		Requested:       9,
+		PinnedAlert:     true,
+		Attempts:        2,
+		ConflictRetries: 1,
+		StartedAt:       started,
+		FinishedAt:      started.Add(3 * time.Second),
+	})
+	m.observe("dev", st, webhooks.ScalingIntent{TargetPool: "workers"}, rule, autoscaler.JobResult{
+		Pool:    "workers",
+		Status:  autoscaler.JobSkipped,
+		Skipped: autoscaler.SkipCooldown,
+	})
+
+	err := testutil.CollectAndCompare(m.intents, strings.NewReader(`
+# HELP pulumiscale_intents_total Intents processed, by source and outcome.
+# TYPE pulumiscale_intents_total counter
+pulumiscale_intents_total{outcome="skipped",pool="workers",source="unknown",stack="dev"} 1
+pulumiscale_intents_total{outcome="succeeded",pool="workers",source="cloudwatch",stack="dev"} 1
+`))
+	if err != nil {
+		t.Error(err)
+	}
+	err = testutil.CollectAndCompare(m.retries, strings.NewReader(`
+# HELP pulumiscale_retries_total Up retries, by kind (conflict for concurrent updates, failure for the retry policy).
+# TYPE pulumiscale_retries_total counter
+pulumiscale_retries_total{kind="conflict",pool="workers",stack="dev"} 1
+pulumiscale_retries_total{kind="failure",pool="workers",stack="dev"} 1
+`))
+	if err != nil {
+		t.Error(err)
+	}
+	if got := testutil.ToFloat64(m.clamps.WithLabelValues("dev", "workers", "max")); got != 1 {
+		t.Errorf("clamps = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.pinnedAlerts.WithLabelValues("dev", "workers", "max")); got != 1 {
+		t.Errorf("pinned alerts = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.cooldownSkips.WithLabelValues("dev", "workers")); got != 1 {
+		t.Errorf("cooldown skips = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.duration); got != 1 {
+		t.Errorf("duration series = %d, want 1 apply observation", got)
+	}
+	if st.current["workers"] != 5 || st.desired["workers"] != 5 {
+		t.Errorf("current = %v, desired = %v; want 5, 5", st.current["workers"], st.desired["workers"])
+	}
+}
+
+func TestCollectPools(t *testing.T) {
+	m := New()
+	e := autoscaler.NewEngine(map[string]autoscaler.ScalingRule{
+		"workers": {PoolName: "workers", Min: 1, Max: 5},
+		"type":    {PoolName: "type", Type: autoscaler.ValueString},
+	}, nil)
+	m.AddEngine("dev", e)
+	e.IntentChan <- webhooks.ScalingIntent{TargetPool: "workers"}
+
+	st := m.stack("dev")
+	st.current["workers"] = 3
+
+	err := testutil.CollectAndCompare(m, strings.NewReader(`
+# HELP pulumiscale_pool_current Last known value of a numeric pool.
+# TYPE pulumiscale_pool_current gauge
+pulumiscale_pool_current{pool="workers",stack="dev"} 3
+# HELP pulumiscale_pool_max Upper guardrail of a numeric pool.
+# TYPE pulumiscale_pool_max gauge
+pulumiscale_pool_max{pool="workers",stack="dev"} 5
+# HELP pulumiscale_pool_min Lower guardrail of a numeric pool.
+# TYPE pulumiscale_pool_min gauge
+pulumiscale_pool_min{pool="workers",stack="dev"} 1
+# HELP pulumiscale_queue_capacity Size of the engine's intent queue.
+# TYPE pulumiscale_queue_capacity gauge
+pulumiscale_queue_capacity{stack="dev"} 100
+# HELP pulumiscale_queue_depth Intents waiting in the engine's queue.
+# TYPE pulumiscale_queue_depth gauge
+pulumiscale_queue_depth{stack="dev"} 1
+`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Pools removed from the rules disappear from the output.
+	e.SetRules(map[string]autoscaler.ScalingRule{})
+	if got := testutil.CollectAndCount(m, "pulumiscale_pool_min", "pulumiscale_pool_current"); got != 0 {
+		t.Errorf("pool series after rule removal = %d, want 0", got)
+	}
+}
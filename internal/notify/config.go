@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sinkConfig is one sink in the notifications file.
+type sinkConfig struct {
+	Name string `yaml:"name"`
+	// Type is webhook, slack or teams.
+	Type     string            `yaml:"type"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	Template string            `yaml:"template"`
+
+	Events []EventType `yaml:"events"`
+	Stacks []string    `yaml:"stacks"`
+	Pools  []string    `yaml:"pools"`
+}
+
+// Load reads a notifier from a YAML file:
+//
+//	retry:
+//	  maxRetries: 3
+//	  baseDelay: 1s
+//	sinks:
+//	  - name: oncall
+//	    type: slack
+//	    url: ${SLACK_WEBHOOK_URL}
+//	    events: [failed, clamped_max]
+//	    pools: ["workers", "gpu-*"]
+//
+// Environment variables in URLs and headers are expanded, so secrets can stay out of the file.
+func Load(path string) (*Notifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Retry Retry        `yaml:"retry"`
+		Sinks []sinkConfig `yaml:"sinks"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(file.Sinks) == 0 {
+		return nil, fmt.Errorf("%s defines no sinks", path)
+	}
+
+	n := &Notifier{Retry: file.Retry}
+	seen := make(map[string]bool, len(file.Sinks))
+	for i, sc := range file.Sinks {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("sinks[%d]", i)
+		}
+		if seen[sc.Name] {
+			return nil, fmt.Errorf("%s: duplicate sink name %q", path, sc.Name)
+		}
+		seen[sc.Name] = true
+		sink, err := sc.sink()
+		if err != nil {
+			return nil, fmt.Errorf("%s: sink %s: %w", path, sc.Name, err)
+		}
+		n.Routes = append(n.Routes, Route{Name: sc.Name, Sink: sink, Events: sc.Events, Stacks: sc.Stacks, Pools: sc.Pools})
+	}
+	return n, nil
+}
+
+func (sc sinkConfig) sink() (Sink, error) {
+	url := os.ExpandEnv(sc.URL)
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	for _, ev := range sc.Events {
+		if !slices.Contains(EventTypes, ev) {
+			return nil, fmt.Errorf("unknown event %q (want one of %v)", ev, EventTypes)
+		}
+	}
+	if sc.Type != "webhook" && (sc.Template != "" || len(sc.Headers) > 0) {
+		return nil, fmt.Errorf("template and headers are only supported by webhook sinks")
+	}
+
+	switch sc.Type {
+	case "webhook":
+		s := &WebhookSink{URL: url}
+		if len(sc.Headers) > 0 {
+			s.Headers = make(map[string]string, len(sc.Headers))
+			for k, v := range sc.Headers {
+				s.Headers[k] = os.ExpandEnv(v)
+			}
+		}
+		if sc.Template != "" {
+			tmpl, err := ParseTemplate(sc.Template)
+			if err != nil {
+				return nil, err
+			}
+			s.Template = tmpl
+		}
+		return s, nil
+	case "slack":
+		return &SlackSink{URL: url}, nil
+	case "teams":
+		return &TeamsSink{URL: url}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q (want webhook, slack or teams)", sc.Type)
+	}
+}
@@ -0,0 +1,329 @@
+// Package notify sends scaling events to chat and webhook sinks.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+// EventType is what happened to a pool.
+type EventType string
+
+const (
+	// EventScaled is sent when an up changed a pool's value.
+	EventScaled EventType = "scaled"
+	// EventFailed is sent when a job failed, whether reading the current value, validating the
+	// intent or running the up.
+	EventFailed EventType = "failed"
+	// EventClampedMax and EventClampedMin are sent when a target was clamped to a guardrail,
+	// whether or not the clamped value changed the pool.
+	EventClampedMax EventType = "clamped_max"
+	EventClampedMin EventType = "clamped_min"
+	// EventCooldown is sent when an intent was dropped because the pool is cooling down.
+	EventCooldown EventType = "cooldown"
+	// EventPinnedMax and EventPinnedMin are sent once when a pool has stayed clamped to a
+	// guardrail for longer than its pinned alert duration.
+	EventPinnedMax EventType = "pinned_max"
+	EventPinnedMin EventType = "pinned_min"
+)
+
+// EventTypes lists every event type, in the order they are documented.
+var EventTypes = []EventType{EventScaled, EventFailed, EventClampedMax, EventClampedMin, EventCooldown, EventPinnedMax, EventPinnedMin}
+
+// Event is a notification about one processed intent.
+type Event struct {
+	Type          EventType             `json:"type"`
+	Time          time.Time             `json:"time"`
+	Stack         string                `json:"stack"`
+	Pool          string                `json:"pool"`
+	Previous      autoscaler.Value      `json:"previous"`
+	Target        autoscaler.Value      `json:"target"`
+	Clamped       autoscaler.ClampBound `json:"clamped,omitempty"`
+	Requested     float64               `json:"requested,omitempty"` // left out for secret pools
+	Secret        bool                  `json:"secret,omitempty"`    // the pool is secret; values are redacted
+	CooldownUntil time.Time             `json:"cooldownUntil,omitzero"`
+	PinnedSince   time.Time             `json:"pinnedSince,omitzero"`
+	Source        string                `json:"source,omitempty"`
+	Reason        string                `json:"reason,omitempty"`
+	Requester     string                `json:"requester,omitempty"`
+	RequestID     string                `json:"requestId,omitempty"`
+	Error         string                `json:"error,omitempty"`
+	UpdateVersion int                   `json:"updateVersion,omitempty"`
+}
+
+// Events returns the events a job produces. Dry runs, and skips other than cooldowns, produce
+// none. A clamped scale produces both a scaled and a clamped event. Values of secret pools
+// are redacted.
+func Events(stack string, intent webhooks.ScalingIntent, rule autoscaler.ScalingRule, result autoscaler.JobResult) []Event {
+	base := Event{
+		Time:          result.FinishedAt,
+		Stack:         stack,
+		Pool:          result.Pool,
+		Previous:      result.Previous,
+		Target:        result.Target,
+		Clamped:       result.Clamped,
+		Requested:     result.Requested,
+		Source:        intent.Source,
+		Reason:        intent.Reason,
+		Requester:     intent.Requester,
+		RequestID:     intent.RequestID,
+		Error:         result.Error,
+		UpdateVersion: result.UpdateVersion,
+		PinnedSince:   result.PinnedSince,
+	}
+	if base.Pool == "" {
+		base.Pool = intent.TargetPool
+	}
+	if rule.Secret {
+		base.Secret, base.Requested = true, 0
+		base.Previous.Secret, base.Target.Secret = true, true
+	}
+	if base.Time.IsZero() {
+		base.Time = time.Now()
+	}
+
+	var types []EventType
+	switch {
+	case result.Status == autoscaler.JobFailed:
+		types = append(types, EventFailed)
+	case result.Status == autoscaler.JobSucceeded:
+		types = append(types, EventScaled)
+	case result.Skipped == autoscaler.SkipCooldown:
+		base.CooldownUntil = result.CooldownUntil
+		types = append(types, EventCooldown)
+	}
+	if result.Status == autoscaler.JobSucceeded || result.Skipped == autoscaler.SkipUnchanged {
+		switch result.Clamped {
+		case autoscaler.ClampMax:
+			types = append(types, EventClampedMax)
+		case autoscaler.ClampMin:
+			types = append(types, EventClampedMin)
+		}
+	}
+	if result.PinnedAlert {
+		switch result.Clamped {
+		case autoscaler.ClampMax:
+			types = append(types, EventPinnedMax)
+		case autoscaler.ClampMin:
+			types = append(types, EventPinnedMin)
+		}
+	}
+
+	out := make([]Event, 0, len(types))
+	for _, t := range types {
+		ev := base
+		ev.Type = t
+		out = append(out, ev)
+	}
+	return out
+}
+
+// requested formats the unclamped target, redacted for secret pools.
+func (ev Event) requested() autoscaler.Value {
+	v := autoscaler.FloatValue(ev.Requested)
+	v.Secret = ev.Secret
+	return v
+}
+
+// Summary is a one-line description of the event, used as the text of chat messages.
+func (ev Event) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s/%s ", ev.Stack, ev.Pool)
+	switch ev.Type {
+	case EventScaled:
+		fmt.Fprintf(&b, "scaled from %s to %s", ev.Previous, ev.Target)
+	case EventFailed:
+		b.WriteString("failed to scale")
+		if ev.Target.Type != "" {
+			fmt.Fprintf(&b, " to %s", ev.Target)
+		}
+		if ev.Error != "" {
+			b.WriteString(": " + ev.Error)
+		}
+	case EventClampedMax, EventClampedMin:
+		fmt.Fprintf(&b, "clamped to its %s of %s (requested %s)", ev.Clamped, ev.Target, ev.requested())
+	case EventPinnedMax, EventPinnedMin:
+		fmt.Fprintf(&b, "has been pinned at its %s of %s", ev.Clamped, ev.Target)
+		if !ev.PinnedSince.IsZero() {
+			fmt.Fprintf(&b, " for %s", ev.Time.Sub(ev.PinnedSince).Round(time.Second))
+		}
+		fmt.Fprintf(&b, " (requested %s)", ev.requested())
+	case EventCooldown:
+		b.WriteString("is cooling down")
+		if !ev.CooldownUntil.IsZero() {
+			fmt.Fprintf(&b, " until %s", ev.CooldownUntil.UTC().Format(time.RFC3339))
+		}
+		b.WriteString("; intent dropped")
+	default:
+		b.WriteString(string(ev.Type))
+	}
+	if ev.Source != "" {
+		b.WriteString(" via " + ev.Source)
+	}
+	return b.String()
+}
+
+// Sink delivers events to one destination.
+type Sink interface {
+	Send(ctx context.Context, ev Event) error
+}
+
+// Route sends the events it matches to its sink. Empty Events, Stacks and Pools match
+// everything; Pools and Stacks are path.Match patterns such as "gpu-*".
+type Route struct {
+	Name   string
+	Sink   Sink
+	Events []EventType
+	Stacks []string
+	Pools  []string
+}
+
+func (r Route) matches(ev Event) bool {
+	return (len(r.Events) == 0 || slices.Contains(r.Events, ev.Type)) &&
+		matchAny(r.Stacks, ev.Stack) && matchAny(r.Pools, ev.Pool)
+}
+
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry configures redelivery of events a sink failed to accept. Zero fields fall back to
+// 3 retries starting at one second; a negative MaxRetries disables retries.
+type Retry struct {
+	MaxRetries int           `yaml:"maxRetries"`
+	BaseDelay  time.Duration `yaml:"baseDelay"`
+}
+
+const (
+	defaultMaxRetries = 3
+	defaultBaseDelay  = time.Second
+	maxDelay          = time.Minute
+)
+
+func (r Retry) maxRetries() int {
+	if r.MaxRetries == 0 {
+		return defaultMaxRetries
+	}
+	return max(r.MaxRetries, 0)
+}
+
+// delay returns the backoff before retry number attempt (0-based), doubling every retry.
+func (r Retry) delay(attempt int) time.Duration {
+	d := r.BaseDelay
+	if d <= 0 {
+		d = defaultBaseDelay
+	}
+	d <<= attempt
+	if d > maxDelay || d <= 0 {
+		d = maxDelay
+	}
+	return d
+}
+
+// Notifier routes events to sinks. Deliveries from Observer run in the background so a slow
+// sink never holds up scaling; Close waits for them.
+type Notifier struct {
+	Routes []Route
+	Retry  Retry
+
+	wg sync.WaitGroup
+}
+
+// Notify sends ev to every matching route, retrying failed deliveries, and returns the
+// errors of the routes that never accepted it.
+func (n *Notifier) Notify(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, r := range n.Routes {
+		if !r.matches(ev) {
+			continue
+		}
+		if err := n.deliver(ctx, r, ev); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) deliver(ctx context.Context, r Route, ev Event) error {
+	retries := n.Retry.maxRetries()
+	for attempt := 0; ; attempt++ {
+		err := r.Sink.Send(ctx, ev)
+		if err == nil || attempt == retries || !retryable(err) {
+			return err
+		}
+		delay := n.Retry.delay(attempt)
+		log.Warn().Err(err).Str("sink", r.Name).Str("pool", ev.Pool).Dur("delay", delay).Msg("Notification failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Observer returns an autoscaler.JobObserver that notifies the routes about every job of
+// the stack's engine. Delivery failures are logged.
+func (n *Notifier) Observer(stack string) autoscaler.JobObserver {
+	return func(intent webhooks.ScalingIntent, rule autoscaler.ScalingRule, result autoscaler.JobResult) {
+		for _, ev := range Events(stack, intent, rule, result) {
+			n.wg.Add(1)
+			go func() {
+				defer n.wg.Done()
+				// The intent's context ends with its request; deliveries outlive it.
+				if err := n.Notify(context.Background(), ev); err != nil {
+					log.Error().Err(err).Str("stack", stack).Str("pool", ev.Pool).Str("event", string(ev.Type)).Msg("Failed to send notification")
+				}
+			}()
+		}
+	}
+}
+
+// Close waits for background deliveries to finish.
+func (n *Notifier) Close() {
+	n.wg.Wait()
+}
+
+// statusError is a delivery rejected by the receiver.
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.status, http.StatusText(e.status))
+	if e.body != "" {
+		msg += ": " + e.body
+	}
+	return msg
+}
+
+// retryable reports whether a delivery may succeed later: network errors, rate limits and
+// server errors are retried; other rejections and bad templates are not.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.status == http.StatusTooManyRequests || se.status >= 500
+	}
+	var te *templateError
+	return !errors.As(err, &te)
+}
@@ -0,0 +1,290 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+// receiver is a local stand-in for a webhook endpoint. It fails the first failures requests
+// with status and records the bodies of the rest.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	status   int
+	calls    int
+	bodies   []map[string]any
+	headers  []http.Header
+}
+
+func newReceiver(t *testing.T, failures, status int) *receiver {
+	rc := &receiver{failures: failures, status: status}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.calls++
+		if rc.calls <= rc.failures {
+			http.Error(w, "try later", rc.status)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("receiver got invalid JSON %q: %v", data, err)
+		}
+		rc.bodies = append(rc.bodies, body)
+		rc.headers = append(rc.headers, r.Header.Clone())
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) received() []map[string]any {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.bodies
+}
+
+func TestEvents(t *testing.T) {
+	intent := webhooks.ScalingIntent{TargetPool: "workers", Source: "cloudwatch"}
+	tests := []struct {
+		name   string
+		result autoscaler.JobResult
+		want   []EventType
+	}{
+		{"scaled", autoscaler.JobResult{Status: autoscaler.JobSucceeded}, []EventType{EventScaled}},
+		{"scaled and clamped", autoscaler.JobResult{Status: autoscaler.JobSucceeded, Clamped: autoscaler.ClampMax, Requested: 50}, []EventType{EventScaled, EventClampedMax}},
+		{"already at min", autoscaler.JobResult{Status: autoscaler.JobSkipped, Skipped: autoscaler.SkipUnchanged, Clamped: autoscaler.ClampMin}, []EventType{EventClampedMin}},
+		{"pinned", autoscaler.JobResult{Status: autoscaler.JobSkipped, Skipped: autoscaler.SkipUnchanged, Clamped: autoscaler.ClampMax, PinnedAlert: true}, []EventType{EventClampedMax, EventPinnedMax}},
+		{"failed", autoscaler.JobResult{Status: autoscaler.JobFailed, Error: "boom"}, []EventType{EventFailed}},
+		{"cooldown", autoscaler.JobResult{Status: autoscaler.JobSkipped, Skipped: autoscaler.SkipCooldown}, []EventType{EventCooldown}},
+		{"dry run", autoscaler.JobResult{Status: autoscaler.JobPreviewed, Clamped: autoscaler.ClampMax}, nil},
+		{"no rule", autoscaler.JobResult{Status: autoscaler.JobSkipped, Skipped: autoscaler.SkipNoRule}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []EventType
+			for _, ev := range Events("dev", intent, autoscaler.ScalingRule{}, tt.result) {
+				if ev.Pool != "workers" || ev.Stack != "dev" || ev.Source != "cloudwatch" {
+					t.Errorf("event = %+v, want the intent's stack, pool and source", ev)
+				}
+				got = append(got, ev.Type)
+			}
+			if strings.Join(eventStrings(got), ",") != strings.Join(eventStrings(tt.want), ",") {
+				t.Errorf("Events() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func eventStrings(types []EventType) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
+
+func TestNotifierRouting(t *testing.T) {
+	oncall := newReceiver(t, 0, 0)
+	gpu := newReceiver(t, 0, 0)
+	n := &Notifier{Routes: []Route{
+		{Name: "oncall", Sink: &SlackSink{URL: oncall.URL}, Events: []EventType{EventFailed}},
+		{Name: "gpu", Sink: &TeamsSink{URL: gpu.URL}, Pools: []string{"gpu-*"}},
+	}}
+
+	observe := n.Observer("prod")
+	observe(webhooks.ScalingIntent{TargetPool: "workers"}, autoscaler.ScalingRule{}, autoscaler.JobResult{Pool: "workers", Status: autoscaler.JobSucceeded})
+	observe(webhooks.ScalingIntent{TargetPool: "workers"}, autoscaler.ScalingRule{}, autoscaler.JobResult{Pool: "workers", Status: autoscaler.JobFailed, Error: "stack locked"})
+	observe(webhooks.ScalingIntent{TargetPool: "gpu-a"}, autoscaler.ScalingRule{}, autoscaler.JobResult{
+		Pool: "gpu-a", Status: autoscaler.JobSucceeded, Previous: autoscaler.IntValue(1), Target: autoscaler.IntValue(2),
+	})
+	n.Close()
+
+	slack := oncall.received()
+	if len(slack) != 1 || !strings.Contains(slack[0]["text"].(string), "prod/workers failed to scale: stack locked") {
+		t.Fatalf("slack received %v, want the one failure", slack)
+	}
+	if att := slack[0]["attachments"].([]any)[0].(map[string]any); att["color"] != "danger" {
+		t.Errorf("slack attachment color = %v, want danger", att["color"])
+	}
+
+	teams := gpu.received()
+	if len(teams) != 1 || teams[0]["type"] != "message" {
+		t.Fatalf("teams received %v, want one message", teams)
+	}
+	card := teams[0]["attachments"].([]any)[0].(map[string]any)["content"].(map[string]any)
+	title := card["body"].([]any)[0].(map[string]any)["text"]
+	if card["type"] != "AdaptiveCard" || title != "prod/gpu-a scaled from 1 to 2" {
+		t.Errorf("teams card = %v", card)
+	}
+}
+
+func TestPinnedSummary(t *testing.T) {
+	since := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ev := Event{
+		Type: EventPinnedMax, Stack: "prod", Pool: "workers", Time: since.Add(20 * time.Minute), PinnedSince: since,
+		Target: autoscaler.IntValue(10), Clamped: autoscaler.ClampMax, Requested: 14,
+	}
+	if got, want := ev.Summary(), "prod/workers has been pinned at its max of 10 for 20m0s (requested 14)"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestSecretEvents(t *testing.T) {
+	rule := autoscaler.ScalingRule{PoolName: "db", Secret: true}
+	result := autoscaler.JobResult{
+		Status: autoscaler.JobSkipped, Skipped: autoscaler.SkipUnchanged, PinnedAlert: true,
+		Previous: autoscaler.IntValue(8000), Target: autoscaler.IntValue(8000), Clamped: autoscaler.ClampMax, Requested: 98765,
+	}
+	events := Events("prod", webhooks.ScalingIntent{TargetPool: "db"}, rule, result)
+	if len(events) != 2 {
+		t.Fatalf("Events() = %d events, want clamped and pinned", len(events))
+	}
+	for _, ev := range events {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var facts []string
+		for _, f := range ev.facts() {
+			facts = append(facts, f[1])
+		}
+		for _, text := range []string{ev.Summary(), string(payload), strings.Join(facts, " ")} {
+			if strings.Contains(text, "98765") || strings.Contains(text, "8000") {
+				t.Errorf("%s event leaks the secret value: %s", ev.Type, text)
+			}
+		}
+		if !ev.Secret {
+			t.Errorf("%s event isn't marked secret", ev.Type)
+		}
+	}
+	if got, want := events[0].Summary(), "prod/db clamped to its max of [secret] (requested [secret])"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestNotifierRetries(t *testing.T) {
+	ev := Event{Type: EventScaled, Stack: "dev", Pool: "workers"}
+	tests := []struct {
+		name      string
+		failures  int
+		status    int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"recovers from server errors", 2, http.StatusBadGateway, false, 3},
+		{"recovers from rate limits", 1, http.StatusTooManyRequests, false, 2},
+		{"gives up after max retries", 10, http.StatusServiceUnavailable, true, 3},
+		{"does not retry client errors", 10, http.StatusForbidden, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newReceiver(t, tt.failures, tt.status)
+			n := &Notifier{
+				Routes: []Route{{Name: "hook", Sink: &WebhookSink{URL: rc.URL}}},
+				Retry:  Retry{MaxRetries: 2, BaseDelay: time.Millisecond},
+			}
+			err := n.Notify(context.Background(), ev)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rc.calls != tt.wantCalls {
+				t.Errorf("receiver got %d requests, want %d", rc.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	rc := newReceiver(t, 0, 0)
+	tmpl, err := ParseTemplate(`{"summary": {{json .Summary}}, "pool": {{json .Pool}}, "to": {{json .Target}}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	sink := &WebhookSink{URL: rc.URL, Template: tmpl, Headers: map[string]string{"Authorization": "Bearer token"}}
+	ev := Event{Type: EventClampedMax, Stack: "dev", Pool: "workers", Target: autoscaler.IntValue(10), Clamped: autoscaler.ClampMax, Requested: 50}
+	if err := sink.Send(context.Background(), ev); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	body := rc.received()[0]
+	if body["summary"] != "dev/workers clamped to its max of 10 (requested 50)" || body["pool"] != "workers" || body["to"] != 10.0 {
+		t.Errorf("body = %v", body)
+	}
+	if got := rc.headers[0].Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+
+	bad, _ := ParseTemplate(`{"pool": {{.Pool}}}`)
+	n := &Notifier{Routes: []Route{{Name: "bad", Sink: &WebhookSink{URL: rc.URL, Template: bad}}}}
+	if err := n.Notify(context.Background(), ev); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("Notify() with an invalid body error = %v, want a template error", err)
+	}
+	if len(rc.received()) != 1 {
+		t.Errorf("invalid body was sent")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("TEST_SLACK_URL", "https://hooks.slack.example/T000")
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "notify.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	n, err := Load(write(`
+retry:
+  maxRetries: 5
+  baseDelay: 2s
+sinks:
+  - name: oncall
+    type: slack
+    url: ${TEST_SLACK_URL}
+    events: [failed, clamped_max]
+    pools: ["workers"]
+  - type: webhook
+    url: https://example.com/hook
+    template: '{"text": {{json .Summary}}}'
+`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if n.Retry.MaxRetries != 5 || n.Retry.BaseDelay != 2*time.Second || len(n.Routes) != 2 {
+		t.Fatalf("Load() = %+v", n)
+	}
+	if slack, ok := n.Routes[0].Sink.(*SlackSink); !ok || slack.URL != "https://hooks.slack.example/T000" {
+		t.Errorf("first sink = %#v, want slack with the expanded URL", n.Routes[0].Sink)
+	}
+	if n.Routes[1].Name != "sinks[1]" || n.Routes[1].Sink.(*WebhookSink).Template == nil {
+		t.Errorf("second route = %+v, want a templated webhook named by index", n.Routes[1])
+	}
+
+	for name, content := range map[string]string{
+		"no sinks":      `sinks: []`,
+		"unknown type":  "sinks:\n  - type: pager\n    url: https://x",
+		"unknown event": "sinks:\n  - type: slack\n    url: https://x\n    events: [exploded]",
+		"missing url":   "sinks:\n  - type: teams",
+		"bad template":  "sinks:\n  - type: webhook\n    url: https://x\n    template: '{{'",
+	} {
+		if _, err := Load(write(content)); err == nil {
+			t.Errorf("Load(%s) succeeded, want an error", name)
+		}
+	}
+}
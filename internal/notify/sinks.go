@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WebhookSink posts events as JSON. Without a template the event itself is posted.
+type WebhookSink struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+
+	// Template renders the request body from the Event. It must produce valid JSON;
+	// the json function encodes a value, e.g. {"text": {{json .Summary}}}.
+	Template *template.Template
+}
+
+// ParseTemplate parses a body template for WebhookSink, with the json function available.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Option("missingkey=error").Parse(text)
+}
+
+// templateError is a body that can't be rendered; retrying won't fix it.
+type templateError struct{ err error }
+
+func (e *templateError) Error() string { return "template: " + e.err.Error() }
+func (e *templateError) Unwrap() error { return e.err }
+
+// Send posts ev to the webhook.
+func (s *WebhookSink) Send(ctx context.Context, ev Event) error {
+	if s.Template == nil {
+		return post(ctx, s.Client, s.URL, s.Headers, ev)
+	}
+	var buf bytes.Buffer
+	if err := s.Template.Execute(&buf, ev); err != nil {
+		return &templateError{err}
+	}
+	if !json.Valid(buf.Bytes()) {
+		return &templateError{fmt.Errorf("rendered body is not valid JSON: %s", truncate(buf.String()))}
+	}
+	return postRaw(ctx, s.Client, s.URL, s.Headers, buf.Bytes())
+}
+
+// SlackSink posts events to a Slack incoming webhook.
+type SlackSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send posts ev as a Slack message with a colour-coded attachment listing its details.
+func (s *SlackSink) Send(ctx context.Context, ev Event) error {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	facts := ev.facts()
+	fields := make([]field, 0, len(facts))
+	for _, f := range facts {
+		fields = append(fields, field{Title: f[0], Value: f[1], Short: len(f[1]) < 40})
+	}
+	msg := map[string]any{
+		"text": ev.Summary(),
+		"attachments": []map[string]any{{
+			"color":  slackColor(ev.Type),
+			"fields": fields,
+			"ts":     ev.Time.Unix(),
+		}},
+	}
+	return post(ctx, s.Client, s.URL, nil, msg)
+}
+
+func slackColor(t EventType) string {
+	switch t {
+	case EventScaled:
+		return "good"
+	case EventFailed, EventPinnedMax, EventPinnedMin:
+		return "danger"
+	default:
+		return "warning"
+	}
+}
+
+// TeamsSink posts events to a Microsoft Teams incoming webhook (or Workflows webhook) as an
+// Adaptive Card.
+type TeamsSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send posts ev as an Adaptive Card with a title and a fact set.
+func (s *TeamsSink) Send(ctx context.Context, ev Event) error {
+	type fact struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	}
+	pairs := ev.facts()
+	facts := make([]fact, 0, len(pairs))
+	for _, f := range pairs {
+		facts = append(facts, fact{Title: f[0], Value: f[1]})
+	}
+	color := "Good"
+	switch ev.Type {
+	case EventFailed, EventPinnedMax, EventPinnedMin:
+		color = "Attention"
+	case EventClampedMax, EventClampedMin, EventCooldown:
+		color = "Warning"
+	}
+	msg := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body": []map[string]any{
+					{"type": "TextBlock", "text": ev.Summary(), "weight": "Bolder", "color": color, "wrap": true},
+					{"type": "FactSet", "facts": facts},
+				},
+			},
+		}},
+	}
+	return post(ctx, s.Client, s.URL, nil, msg)
+}
+
+// facts lists the event's set fields as title/value pairs for chat cards.
+func (ev Event) facts() [][2]string {
+	facts := [][2]string{{"Stack", ev.Stack}, {"Pool", ev.Pool}, {"Event", string(ev.Type)}}
+	add := func(title, value string) {
+		if value != "" {
+			facts = append(facts, [2]string{title, value})
+		}
+	}
+	if ev.Previous.Type != "" {
+		add("Previous", ev.Previous.String())
+	}
+	if ev.Target.Type != "" {
+		add("Target", ev.Target.String())
+	}
+	if ev.Clamped != "" {
+		add("Requested", fmt.Sprintf("%s (clamped to %s)", ev.requested(), ev.Clamped))
+	}
+	if !ev.PinnedSince.IsZero() {
+		add("Pinned since", ev.PinnedSince.UTC().Format(time.RFC3339))
+	}
+	if !ev.CooldownUntil.IsZero() {
+		add("Cooldown until", ev.CooldownUntil.UTC().Format(time.RFC3339))
+	}
+	add("Source", ev.Source)
+	add("Reason", ev.Reason)
+	add("Requester", ev.Requester)
+	add("Error", ev.Error)
+	if ev.UpdateVersion > 0 {
+		add("Update", fmt.Sprint(ev.UpdateVersion))
+	}
+	return facts
+}
+
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return postRaw(ctx, client, url, headers, body)
+}
+
+// postRaw posts a JSON body and treats any non-2xx response as a failed delivery.
+func postRaw(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	if client == nil {
+		client = defaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{status: resp.StatusCode, body: truncate(strings.TrimSpace(string(msg)))}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func truncate(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
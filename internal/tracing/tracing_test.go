@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an in-process OTLP/HTTP trace receiver.
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	out, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Write(out)
+}
+
+func TestMiddlewareExportsSpans(t *testing.T) {
+	ctx := context.Background()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	shutdown, err := Setup(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Post("/webhook/{pool}/delta", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodPost, "/webhook/workers/delta", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Shutdown flushes the batcher to the collector.
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.spans) != 1 {
+		t.Fatalf("collector received %d spans, want 1", len(c.spans))
+	}
+	span := c.spans[0]
+	if span.Name != "POST /webhook/{pool}/delta" {
+		t.Errorf("span name = %q", span.Name)
+	}
+	if got := hex.EncodeToString(span.TraceId); got != traceID {
+		t.Errorf("trace ID = %s, want the incoming %s", got, traceID)
+	}
+	if got := hex.EncodeToString(span.ParentSpanId); got != spanID {
+		t.Errorf("parent span ID = %s, want the incoming %s", got, spanID)
+	}
+}
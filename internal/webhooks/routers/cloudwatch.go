@@ -94,7 +94,7 @@ func CloudWatchHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFun
 			Reason:     "SNS Notification Received",
 		}
 
-		intentChan <- intent
+		intentChan <- intent.Queued(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -55,7 +55,7 @@ func TestCloudWatchHandler(t *testing.T) {
 			t.Errorf("Wrong action: got %s want delta", intent.Action)
 		}
 		if intent.Value != 1 { // Default CloudWatch delta
-			t.Errorf("Wrong value: got %d want 1", intent.Value)
+			t.Errorf("Wrong value: got %v want 1", intent.Value)
 		}
 		if intent.Source != "cloudwatch" {
 			t.Errorf("Wrong source: got %s want cloudwatch", intent.Source)
@@ -13,30 +13,43 @@ func CountHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		pool := chi.URLParam(r, "pool")
 		dryRun := r.URL.Query().Get("dryRun") == "true"
-		
+
+		// Value is a number for int/float pools or a string for string pools.
 		var req struct {
-			Value int `json:"value"`
+			Value any `json:"value"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
 
-		if req.Value < 0 {
-			http.Error(w, "Value must be non-negative", http.StatusBadRequest)
-			return
-		}
-
 		intent := webhooks.ScalingIntent{
 			TargetPool: pool,
 			Action:     webhooks.ActionSet,
-			Value:      req.Value,
 			Source:     "api_count",
 			Reason:     "Manual Set Request",
 			DryRun:     dryRun,
 		}
 
-		intentChan <- intent
+		switch v := req.Value.(type) {
+		case float64:
+			if v < 0 {
+				http.Error(w, "Value must be non-negative", http.StatusBadRequest)
+				return
+			}
+			intent.Value = v
+		case string:
+			if v == "" {
+				http.Error(w, "Value must not be empty", http.StatusBadRequest)
+				return
+			}
+			intent.Text = v
+		default:
+			http.Error(w, "Value must be a number or a string", http.StatusBadRequest)
+			return
+		}
+
+		intentChan <- intent.Queued(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -13,9 +13,9 @@ func DeltaHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		pool := chi.URLParam(r, "pool")
 		dryRun := r.URL.Query().Get("dryRun") == "true"
-		
+
 		var req struct {
-			Delta int `json:"delta"`
+			Delta float64 `json:"delta"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
@@ -36,7 +36,7 @@ func DeltaHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFunc {
 			DryRun:     dryRun,
 		}
 
-		intentChan <- intent
+		intentChan <- intent.Queued(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}
 }
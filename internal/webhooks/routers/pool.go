@@ -0,0 +1,35 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+)
+
+// NewPoolRouter serves every webhook format under /{pool}/ for the given pools.
+// Requests for unknown pools get a 404.
+func NewPoolRouter(intentChan chan<- webhooks.ScalingIntent, pools []string) *chi.Mux {
+	known := make(map[string]bool, len(pools))
+	for _, p := range pools {
+		known[p] = true
+	}
+
+	wr := chi.NewRouter()
+	wr.Route("/{pool}", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !known[chi.URLParam(r, "pool")] {
+					http.Error(w, "Unknown pool", http.StatusNotFound)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		r.Post("/cloudwatch", CloudWatchHandler(intentChan))
+		r.Post("/prometheus", PrometheusHandler(intentChan))
+		r.Post("/count", CountHandler(intentChan))
+		r.Post("/delta", DeltaHandler(intentChan))
+	})
+	return wr
+}
@@ -65,7 +65,7 @@ func PrometheusHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFun
 					continue // Should not happen with router
 				}
 			}
-			
+
 			// If we want to strictly follow "mapping pool label", we should check:
 			if alert.Labels["pool"] != "" && alert.Labels["pool"] != pathPool {
 				// Mismatch? Log warning, skip?
@@ -76,9 +76,9 @@ func PrometheusHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFun
 			// Prometheus alerts don't inherently carry "delta=+1".
 			// We can look for annotations like "scale_action" or "scale_delta".
 			// Defaults to Delta +1 if not specified.
-			delta := 1
+			delta := 1.0
 			// Basic logic: Alert Firing = Scale Up (or down if specified).
-			
+
 			intent := webhooks.ScalingIntent{
 				TargetPool: poolLabel,
 				Action:     webhooks.ActionDelta,
@@ -86,7 +86,7 @@ func PrometheusHandler(intentChan chan<- webhooks.ScalingIntent) http.HandlerFun
 				Source:     "prometheus",
 				Reason:     fmt.Sprintf("Alert %v firing", alert.Labels["alertname"]),
 			}
-			intentChan <- intent
+			intentChan <- intent.Queued(r.Context())
 		}
 
 		w.WriteHeader(http.StatusOK)
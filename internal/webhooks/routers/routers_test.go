@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rshade/pulumi-scale/internal/webhooks"
 )
 
@@ -36,7 +37,7 @@ func TestCountHandler(t *testing.T) {
 			t.Errorf("Wrong pool: got %s want worker-pool", intent.TargetPool)
 		}
 		if intent.Value != 10 {
-			t.Errorf("Wrong value: got %d want 10", intent.Value)
+			t.Errorf("Wrong value: got %v want 10", intent.Value)
 		}
 		if intent.Action != webhooks.ActionSet {
 			t.Errorf("Wrong action: got %s want set", intent.Action)
@@ -67,7 +68,7 @@ func TestDeltaHandler(t *testing.T) {
 	select {
 	case intent := <-intentChan:
 		if intent.Value != -1 {
-			t.Errorf("Wrong value: got %d want -1", intent.Value)
+			t.Errorf("Wrong value: got %v want -1", intent.Value)
 		}
 		if intent.Action != webhooks.ActionDelta {
 			t.Errorf("Wrong action: got %s want delta", intent.Action)
@@ -76,3 +77,90 @@ func TestDeltaHandler(t *testing.T) {
 		t.Error("No intent received")
 	}
 }
+
+func TestCountHandlerTypedValues(t *testing.T) {
+	intentChan := make(chan webhooks.ScalingIntent, 1)
+
+	r := chi.NewRouter()
+	r.Post("/webhook/{pool}/count", CountHandler(intentChan))
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     webhooks.ScalingIntent
+	}{
+		{name: "float", body: `{"value": 0.5}`, wantCode: http.StatusOK, want: webhooks.ScalingIntent{Value: 0.5}},
+		{name: "string", body: `{"value": "m5.large"}`, wantCode: http.StatusOK, want: webhooks.ScalingIntent{Text: "m5.large"}},
+		{name: "negative", body: `{"value": -1}`, wantCode: http.StatusBadRequest},
+		{name: "bool", body: `{"value": true}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/webhook/worker-pool/count", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Handler returned wrong status code: got %v want %v", w.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			intent := <-intentChan
+			if intent.Value != tt.want.Value || intent.Text != tt.want.Text {
+				t.Errorf("Wrong value: got %v/%q want %v/%q", intent.Value, intent.Text, tt.want.Value, tt.want.Text)
+			}
+		})
+	}
+}
+
+func TestNewPoolRouter(t *testing.T) {
+	intentChan := make(chan webhooks.ScalingIntent, 1)
+	router := NewPoolRouter(intentChan, []string{"workers"})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/workers/count", http.StatusOK},
+		{"/api/count", http.StatusNotFound},
+		{"/workers/unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(`{"value": 3}`)))
+		if w.Code != tt.want {
+			t.Errorf("POST %s = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+	if len(intentChan) != 1 {
+		t.Errorf("got %d intents, want 1", len(intentChan))
+	}
+}
+
+func TestIntentCarriesRequestContext(t *testing.T) {
+	intentChan := make(chan webhooks.ScalingIntent, 1)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(webhooks.WithRequester(r.Context(), "alertmanager")))
+		})
+	})
+	r.Post("/webhook/{pool}/delta", DeltaHandler(intentChan))
+
+	req := httptest.NewRequest("POST", "/webhook/worker-pool/delta", bytes.NewBufferString(`{"delta": 1}`))
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	intent := <-intentChan
+	if intent.RequestID != "req-123" || intent.Requester != "alertmanager" {
+		t.Errorf("RequestID = %q, Requester = %q; want req-123, alertmanager", intent.RequestID, intent.Requester)
+	}
+	if intent.QueuedAt.IsZero() {
+		t.Error("QueuedAt not set")
+	}
+}
@@ -1,25 +1,72 @@
 package webhooks
 
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type IntentAction string
 
 const (
-    ActionSet   IntentAction = "set"
-    ActionDelta IntentAction = "delta"
+	ActionSet   IntentAction = "set"
+	ActionDelta IntentAction = "delta"
 )
 
 type ScalingIntent struct {
-    // The pool to target (must match a ScalingRule.PoolName)
-    TargetPool string
-
-    // What to do
-    Action IntentAction
-
-    // The value (e.g., 50 for Set, +1/-1 for Delta)
-    Value int
-    
-    // Metadata for logging
-    Source string // "cloudwatch", "prometheus", "manual"
-    Reason string // "CPU > 80%", "Alarm Triggered"
-    
-    DryRun bool
+	// The pool to target (must match a ScalingRule.PoolName)
+	TargetPool string
+
+	// What to do
+	Action IntentAction
+
+	// The value (e.g., 50 for Set, +1/-1 for Delta). Fractional for float pools.
+	Value float64
+
+	// The value for string pools (e.g., an instance type). Only valid with ActionSet.
+	Text string
+
+	// Metadata for logging
+	Source string // "cloudwatch", "prometheus", "manual"
+	Reason string // "CPU > 80%", "Alarm Triggered"
+
+	// Who sent the intent and the ID of the request that carried it, for the audit log.
+	Requester string
+	RequestID string
+
+	DryRun bool
+
+	// Trace context of the request that produced the intent and when it was queued,
+	// so processing continues the trace and records the time spent waiting.
+	SpanContext trace.SpanContext
+	QueuedAt    time.Time
+}
+
+// Queued returns the intent stamped with the span in ctx and the current time, ready to send to the engine.
+// The request ID and requester are taken from ctx unless already set.
+func (i ScalingIntent) Queued(ctx context.Context) ScalingIntent {
+	i.SpanContext = trace.SpanContextFromContext(ctx)
+	i.QueuedAt = time.Now()
+	if i.RequestID == "" {
+		i.RequestID = middleware.GetReqID(ctx)
+	}
+	if i.Requester == "" {
+		i.Requester = RequesterFromContext(ctx)
+	}
+	return i
+}
+
+type requesterKey struct{}
+
+// WithRequester returns a copy of ctx that identifies who sent the request.
+func WithRequester(ctx context.Context, requester string) context.Context {
+	return context.WithValue(ctx, requesterKey{}, requester)
+}
+
+// RequesterFromContext returns the requester stored by WithRequester, or "".
+func RequesterFromContext(ctx context.Context) string {
+	requester, _ := ctx.Value(requesterKey{}).(string)
+	return requester
 }
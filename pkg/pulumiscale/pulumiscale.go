@@ -0,0 +1,198 @@
+// Package pulumiscale embeds the PulumiScale autoscaler in a Go program.
+//
+// A Scaler owns one stack, its scaling rules and the engine that applies intents to it.
+// Intents can come from the built-in webhook handlers (Handler), from the caller's own
+// sources (Intents), or be applied synchronously (Scale):
+//
+//	scaler := pulumiscale.NewInline("dev", "infra", program)
+//	if err := scaler.SetRule("workers", pulumiscale.Rule{
+//		TargetURN: "urn:pulumi:dev::infra::aws:autoscaling/group:Group::workers",
+//		ConfigKey: "workerCount",
+//		Min:       1,
+//		Max:       10,
+//	}); err != nil {
+//		return err
+//	}
+//	go scaler.Run(ctx)
+//	router.Mount("/scale", scaler.Handler())
+package pulumiscale
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/rshade/pulumi-scale/internal/autoscaler"
+	"github.com/rshade/pulumi-scale/internal/webhooks"
+	"github.com/rshade/pulumi-scale/internal/webhooks/routers"
+)
+
+type (
+	// Rule is the scaling rule for one pool; see the README for its fields.
+	Rule            = autoscaler.ScalingRule
+	DerivedKey      = autoscaler.DerivedKey
+	RetryPolicy     = autoscaler.RetryPolicy
+	CurrentSource   = autoscaler.CurrentSource
+	DriftConfig     = autoscaler.DriftConfig
+	DriftPolicy     = autoscaler.DriftPolicy
+	FailurePolicy   = autoscaler.FailurePolicy
+	ScalingStrategy = autoscaler.ScalingStrategy
+	Value           = autoscaler.Value
+	ValueType       = autoscaler.ValueType
+	JobResult       = autoscaler.JobResult
+
+	// Intent asks the engine to change a pool.
+	Intent       = webhooks.ScalingIntent
+	IntentAction = webhooks.IntentAction
+
+	// ValidationErrors lists every problem with a rule, located by JSON pointer.
+	ValidationErrors = autoscaler.ValidationErrors
+	FieldError       = autoscaler.FieldError
+
+	// RuleSource provides rules to LoadRules, in addition to the stack's output and config.
+	RuleSource = autoscaler.RuleSource
+)
+
+const (
+	ActionSet   = webhooks.ActionSet
+	ActionDelta = webhooks.ActionDelta
+
+	ValueInt    = autoscaler.ValueInt
+	ValueFloat  = autoscaler.ValueFloat
+	ValueString = autoscaler.ValueString
+
+	StrategyIncremental = autoscaler.StrategyIncremental
+	StrategyAbsolute    = autoscaler.StrategyAbsolute
+
+	FailureRollback = autoscaler.FailureRollback
+	FailureLeave    = autoscaler.FailureLeave
+	FailureRetry    = autoscaler.FailureRetry
+
+	DriftReport  = autoscaler.DriftReport
+	DriftAdopt   = autoscaler.DriftAdopt
+	DriftCorrect = autoscaler.DriftCorrect
+)
+
+// IntValue returns a whole-number Value.
+func IntValue(n int) Value { return autoscaler.IntValue(n) }
+
+// FloatValue returns a fractional Value.
+func FloatValue(f float64) Value { return autoscaler.FloatValue(f) }
+
+// StringValue returns a string Value, e.g. an instance type.
+func StringValue(s string) Value { return autoscaler.StringValue(s) }
+
+// Scaler scales one stack.
+type Scaler struct {
+	state  *autoscaler.StateManager
+	engine *autoscaler.Engine
+	loader *autoscaler.ConfigLoader
+
+	mu     sync.Mutex // serializes rule changes
+	routes atomic.Pointer[chi.Mux]
+}
+
+// NewInline scales a stack whose program is an inline Go function, using
+// auto.UpsertStackInlineSource. Pass auto.WorkDir to keep stack config across restarts.
+func NewInline(stackName, projectName string, program pulumi.RunFunc, opts ...auto.LocalWorkspaceOption) *Scaler {
+	return newScaler(autoscaler.NewInlineStateManager(stackName, projectName, program, opts...))
+}
+
+// NewLocal scales a stack whose program lives in workDir.
+func NewLocal(stackName, workDir string) *Scaler {
+	return newScaler(autoscaler.NewStateManager(stackName, workDir))
+}
+
+func newScaler(state *autoscaler.StateManager) *Scaler {
+	s := &Scaler{
+		state:  state,
+		engine: autoscaler.NewEngine(map[string]autoscaler.ScalingRule{}, state),
+		loader: autoscaler.NewConfigLoaderFor(state),
+	}
+	s.loader.AddSource(autoscaler.NewConfigSourceFor(state))
+	s.routes.Store(routers.NewPoolRouter(s.engine.IntentChan, nil))
+	return s
+}
+
+// SetRule adds or replaces the rule for a pool. Invalid rules are rejected with
+// ValidationErrors listing every problem.
+func (s *Scaler) SetRule(pool string, rule Rule) error {
+	rule.PoolName = pool
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := s.engine.RulesSnapshot()
+	rules[pool] = rule
+	s.setRules(rules)
+	return nil
+}
+
+// RemoveRule stops scaling a pool.
+func (s *Scaler) RemoveRule(pool string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := s.engine.RulesSnapshot()
+	delete(rules, pool)
+	s.setRules(rules)
+}
+
+// Rules returns a copy of the current rules.
+func (s *Scaler) Rules() map[string]Rule {
+	return s.engine.RulesSnapshot()
+}
+
+// AddRuleSource adds a source for LoadRules that takes precedence over those added before it.
+func (s *Scaler) AddRuleSource(src RuleSource) {
+	s.loader.AddSource(src)
+}
+
+// LoadRules replaces the rules with those from the stack's "pulumiscale" output,
+// its "pulumiscale:rules" config and any added sources.
+func (s *Scaler) LoadRules(ctx context.Context) error {
+	rules, err := s.loader.LoadRules(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setRules(rules)
+	return nil
+}
+
+func (s *Scaler) setRules(rules map[string]autoscaler.ScalingRule) {
+	s.engine.SetRules(rules)
+	s.routes.Store(routers.NewPoolRouter(s.engine.IntentChan, slices.Sorted(maps.Keys(rules))))
+}
+
+// Intents returns the queue Run consumes, for callers with their own intent sources.
+func (s *Scaler) Intents() chan<- Intent {
+	return s.engine.IntentChan
+}
+
+// Run processes queued intents until ctx is cancelled.
+func (s *Scaler) Run(ctx context.Context) {
+	s.engine.Start(ctx)
+}
+
+// Scale applies an intent immediately and returns its result. It is serialized with Run.
+func (s *Scaler) Scale(ctx context.Context, intent Intent) JobResult {
+	return s.engine.ProcessIntent(ctx, intent)
+}
+
+// Handler serves the webhook formats at /{pool}/cloudwatch, /{pool}/prometheus, /{pool}/count
+// and /{pool}/delta, queuing intents for Run. Mount it in any router, e.g. with chi's Mount or
+// http.StripPrefix.
+func (s *Scaler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.routes.Load().ServeHTTP(w, r)
+	})
+}
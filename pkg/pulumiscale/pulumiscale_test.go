@@ -0,0 +1,53 @@
+package pulumiscale
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestScalerRules(t *testing.T) {
+	scaler := NewInline("dev", "infra", func(*pulumi.Context) error { return nil })
+
+	err := scaler.SetRule("workers", Rule{ConfigKey: "workerCount", Min: 5, Max: 1})
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Fatalf("SetRule() error = %v, want 2 validation errors", err)
+	}
+
+	rule := Rule{
+		TargetURN: "urn:pulumi:dev::infra::aws:autoscaling/group:Group::workers",
+		ConfigKey: "workerCount",
+		Max:       10,
+	}
+	if err := scaler.SetRule("workers", rule); err != nil {
+		t.Fatalf("SetRule() error = %v", err)
+	}
+	if got := scaler.Rules()["workers"]; got.PoolName != "workers" {
+		t.Errorf("PoolName = %q, want workers", got.PoolName)
+	}
+
+	// The handler follows rule changes and queues intents for Run.
+	router := chi.NewRouter()
+	router.Mount("/scale", scaler.Handler())
+	post := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"value": 3}`)))
+		return w.Code
+	}
+	if code := post("/scale/workers/count"); code != http.StatusOK {
+		t.Errorf("POST workers = %d, want 200", code)
+	}
+	scaler.RemoveRule("workers")
+	if code := post("/scale/workers/count"); code != http.StatusNotFound {
+		t.Errorf("POST removed pool = %d, want 404", code)
+	}
+	if n := len(scaler.engine.IntentChan); n != 1 {
+		t.Errorf("queued %d intents, want 1", n)
+	}
+}
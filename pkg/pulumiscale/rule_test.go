@@ -0,0 +1,56 @@
+package pulumiscale_test
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/rshade/pulumi-scale/pkg/pulumiscale"
+)
+
+// TestFullRule builds a rule using every field type from outside the module's internal packages.
+func TestFullRule(t *testing.T) {
+	const asgURN = "urn:pulumi:dev::infra::aws:autoscaling/group:Group::workers"
+	scaler := pulumiscale.NewInline("dev", "infra", func(*pulumi.Context) error { return nil })
+
+	rule := pulumiscale.Rule{
+		TargetURN:      asgURN,
+		ConfigKey:      "workerCount",
+		DerivedKeys:    []pulumiscale.DerivedKey{{ConfigKey: "maxSize", Value: "desired + 2"}},
+		Type:           pulumiscale.ValueInt,
+		Min:            1,
+		Max:            10,
+		Strategy:       pulumiscale.StrategyIncremental,
+		OnFailure:      pulumiscale.FailureRetry,
+		FailureRetries: 2,
+		Retry:          pulumiscale.RetryPolicy{MaxRetries: 3, BaseDelaySeconds: 2},
+		CurrentFrom:    &pulumiscale.CurrentSource{Property: "desiredCapacity"},
+		Drift:          &pulumiscale.DriftConfig{Property: "desiredCapacity", Policy: pulumiscale.DriftCorrect},
+	}
+	if err := scaler.SetRule("workers", rule); err != nil {
+		t.Fatalf("SetRule() error = %v", err)
+	}
+	got := scaler.Rules()["workers"]
+	if got.OnFailure != pulumiscale.FailureRetry || got.Drift.Policy != pulumiscale.DriftCorrect || got.CurrentFrom.Property != "desiredCapacity" {
+		t.Errorf("rule = %+v", got)
+	}
+
+	if err := scaler.SetRule("type", pulumiscale.Rule{
+		TargetURN: asgURN,
+		ConfigKey: "instanceType",
+		Type:      pulumiscale.ValueString,
+		Allowed:   []string{"m5.large", "m5.xlarge"},
+	}); err != nil {
+		t.Fatalf("SetRule() string pool error = %v", err)
+	}
+
+	if v := pulumiscale.IntValue(3); v.Type != pulumiscale.ValueInt || v.Number != 3 {
+		t.Errorf("IntValue() = %+v", v)
+	}
+	if v := pulumiscale.FloatValue(0.5); v.Type != pulumiscale.ValueFloat || v.Raw() != "0.5" {
+		t.Errorf("FloatValue() = %+v", v)
+	}
+	if v := pulumiscale.StringValue("m5.large"); v.Type != pulumiscale.ValueString || v.Raw() != "m5.large" {
+		t.Errorf("StringValue() = %+v", v)
+	}
+}
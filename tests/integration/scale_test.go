@@ -36,7 +36,7 @@ func TestScaleFlow(t *testing.T) {
 	}
 
 	start := time.Now()
-	err := state.Apply(ctx, rule, 3)
+	_, err := state.Apply(ctx, rule, autoscaler.IntValue(3))
 	if err != nil {
 		t.Errorf("Apply failed: %v", err)
 	}